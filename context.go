@@ -0,0 +1,258 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reContextOrigRange = regexp.MustCompile(`^\*\*\* (\d+)(?:,(\d+))? \*\*\*\*$`)
+	reContextNewRange  = regexp.MustCompile(`^--- (\d+)(?:,(\d+))? ----$`)
+)
+
+// contextLine is a line from one side of a hunk in a context diff, with the
+// mark before it: ' ' for unchanged, '-' for removed, '+' for added and '!'
+// for changed.
+type contextLine struct {
+	mark      byte
+	content   string
+	noNewline bool
+}
+
+// contextHunk is a hunk from a context diff, as the separate lists of lines
+// for each side, with the first and last line numbers of each side as they
+// are written in the diff.
+type contextHunk struct {
+	origFirst, origLast int
+	newFirst, newLast   int
+	origLines, newLines []contextLine
+
+	rawStart, rawEnd int
+}
+
+// ParseContext parses a diff in the older context format of "diff -c", with
+// "***" and "---" file headers and "!" marking changed lines. The result
+// is the same as parsing the unified diff of the same change, including the
+// Positions of the lines, which count lines as if the diff was unified.
+func ParseContext(s string) (*Diff, error) {
+	diff := &Diff{Raw: s}
+
+	var file *DiffFile
+	var hunk *contextHunk
+	var section *[]contextLine
+	position := 0
+
+	// flush adds the current hunk to the current file.
+	flush := func() error {
+		if hunk == nil {
+			return nil
+		}
+		if len(file.Hunks) > 0 {
+			position++
+		}
+		h, err := hunk.diffHunk(&position)
+		if err != nil {
+			return err
+		}
+		h.RawStart, h.RawEnd = hunk.rawStart, hunk.rawEnd
+		file.Hunks = append(file.Hunks, h)
+		hunk, section = nil, nil
+		return nil
+	}
+
+	lineEnd := 0
+	for i, l := range strings.Split(s, "\n") {
+		lineStart := lineEnd
+		lineEnd += len(l) + 1
+		if lineEnd > len(s) {
+			lineEnd = len(s)
+		}
+
+		var err error
+		inHunk := true
+		switch {
+		case l == "***************":
+			if file == nil {
+				return nil, fmt.Errorf("line %d: hunk before file header", i+1)
+			}
+			err = flush()
+			hunk = &contextHunk{rawStart: lineStart}
+		case hunk != nil && section == nil && reContextOrigRange.MatchString(l):
+			m := reContextOrigRange.FindStringSubmatch(l)
+			hunk.origFirst, hunk.origLast, err = contextRange(m)
+			section = &hunk.origLines
+		case hunk != nil && reContextNewRange.MatchString(l):
+			m := reContextNewRange.FindStringSubmatch(l)
+			hunk.newFirst, hunk.newLast, err = contextRange(m)
+			section = &hunk.newLines
+		case strings.HasPrefix(l, "*** "):
+			if err := flush(); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			file = &DiffFile{
+				Mode:       MODIFIED,
+				DiffHeader: l,
+				RawStart:   lineStart,
+			}
+			inHunk = false
+			diff.Files = append(diff.Files, file)
+			position = 0
+			name, annotation, ok := headerName(l[4:], 'a')
+			file.OrigAnnotation = annotation
+			if ok {
+				file.OrigName = name
+			} else {
+				file.Mode = NEW
+			}
+		case file != nil && hunk == nil && strings.HasPrefix(l, "--- "):
+			file.DiffHeader += "\n" + l
+			inHunk = false
+			name, annotation, ok := headerName(l[4:], 'b')
+			file.NewAnnotation = annotation
+			switch {
+			case !ok:
+				file.Mode = DELETED
+				file.NewName = file.OrigName
+			case file.Mode == NEW:
+				file.OrigName = name
+				file.NewName = name
+			default:
+				file.NewName = name
+			}
+		case section != nil && strings.HasPrefix(l, `\ `):
+			if n := len(*section); n > 0 {
+				(*section)[n-1].noNewline = true
+			}
+		case section != nil && len(l) >= 2 && l[1] == ' ' && strings.IndexByte(" -+!", l[0]) >= 0:
+			*section = append(*section, contextLine{mark: l[0], content: l[2:]})
+		default:
+			// Anything else, such as the "diff -c" command line before each
+			// file, ends the hunk.
+			err = flush()
+			inHunk = false
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if hunk != nil && inHunk {
+			hunk.rawEnd = lineEnd
+		}
+		if file != nil {
+			file.RawEnd = lineEnd
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+// contextRange parses the first and last line numbers from a range in a
+// context diff, which only has one number if they're the same.
+func contextRange(m []string) (first, last int, err error) {
+	first, err = strconv.Atoi(m[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	last = first
+	if m[2] != "" {
+		last, err = strconv.Atoi(m[2])
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return first, last, nil
+}
+
+// diffHunk converts the hunk to a unified DiffHunk, interleaving the lines
+// from each side. position is the Position of the hunk's header, and is
+// updated to the Position of its last line.
+func (h *contextHunk) diffHunk(position *int) (*DiffHunk, error) {
+	// A side without any changes is left out, since it's just the
+	// unchanged lines from the other side.
+	orig, updated := h.origLines, h.newLines
+	if len(orig) == 0 {
+		orig = unchangedLines(updated)
+	}
+	if len(updated) == 0 {
+		updated = unchangedLines(orig)
+	}
+
+	var lines []DiffLine
+	add := func(mode DiffLineMode, l contextLine) {
+		lines = append(lines, DiffLine{Mode: mode, Content: l.content, NoNewline: l.noNewline})
+	}
+	i, j := 0, 0
+	for i < len(orig) || j < len(updated) {
+		switch {
+		case i < len(orig) && orig[i].mark == '-':
+			add(REMOVED, orig[i])
+			i++
+		case j < len(updated) && updated[j].mark == '+':
+			add(ADDED, updated[j])
+			j++
+		case i < len(orig) && orig[i].mark == '!', j < len(updated) && updated[j].mark == '!':
+			// The old version of a block of changed lines, followed by the
+			// new version.
+			for ; i < len(orig) && orig[i].mark == '!'; i++ {
+				add(REMOVED, orig[i])
+			}
+			for ; j < len(updated) && updated[j].mark == '!'; j++ {
+				add(ADDED, updated[j])
+			}
+		case i < len(orig) && j < len(updated) && orig[i].mark == ' ' && updated[j].mark == ' ':
+			add(UNCHANGED, updated[j])
+			i++
+			j++
+		default:
+			return nil, errors.New("sides of context hunk don't match")
+		}
+	}
+
+	hunk := &DiffHunk{}
+	hunk.OrigRange.Start, hunk.OrigRange.Length = contextStart(h.origFirst, h.origLast, len(orig)), len(orig)
+	hunk.NewRange.Start, hunk.NewRange.Length = contextStart(h.newFirst, h.newLast, len(updated)), len(updated)
+
+	origNumber, newNumber := hunk.OrigRange.first(), hunk.NewRange.first()
+	hunkPosition := 0
+	for _, l := range lines {
+		*position++
+		hunkPosition++
+		l.Position = *position
+		l.HunkPosition = hunkPosition
+		hunk.addLine(l, &origNumber, &newNumber, false)
+		// The unified diff has a marker after the line.
+		if l.NoNewline {
+			*position++
+			hunkPosition++
+		}
+	}
+	return hunk, nil
+}
+
+// contextStart returns the Start of a side of a hunk in a context diff,
+// following the unified convention that an empty side starts at the line
+// before it. Context diffs write only that line for an empty side.
+func contextStart(first, last, length int) int {
+	if length == 0 {
+		return last
+	}
+	return first
+}
+
+func unchangedLines(lines []contextLine) []contextLine {
+	var unchanged []contextLine
+	for _, l := range lines {
+		if l.mark == ' ' {
+			unchanged = append(unchanged, l)
+		}
+	}
+	return unchanged
+}
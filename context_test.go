@@ -0,0 +1,168 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContext(t *testing.T) {
+	diff, err := ParseContext(`diff -c old/hello.c new/hello.c
+*** old/hello.c	2020-01-01 12:00:00.000000000 +0000
+--- new/hello.c	2020-01-02 12:00:00.000000000 +0000
+***************
+*** 1,6 ****
+  #include <stdio.h>
+  
+! int main() {
+! 	printf("hello\n");
+  	return 0;
+- }
+--- 1,7 ----
+  #include <stdio.h>
+  
+! int main(void) {
+! 	printf("hello, world\n");
+  	return 0;
++ 	/* done */
++ }
+***************
+*** 20,21 ****
+--- 21,23 ----
+  int x;
++ int y;
+  int z;
+diff -c old/gone.txt new/gone.txt
+*** old/gone.txt	2020-01-01 12:00:00.000000000 +0000
+--- new/gone.txt	2020-01-02 12:00:00.000000000 +0000
+***************
+*** 1,3 ****
+  a
+- b
+  c
+--- 1,2 ----
+`)
+	require.NoError(t, err)
+
+	unified, err := Parse(`diff -u old/hello.c new/hello.c
+--- old/hello.c	2020-01-01 12:00:00.000000000 +0000
++++ new/hello.c	2020-01-02 12:00:00.000000000 +0000
+@@ -1,6 +1,7 @@
+ #include <stdio.h>
+ 
+-int main() {
+-	printf("hello\n");
++int main(void) {
++	printf("hello, world\n");
+ 	return 0;
+-}
++	/* done */
++}
+@@ -20,2 +21,3 @@
+ int x;
++int y;
+ int z;
+diff -u old/gone.txt new/gone.txt
+--- old/gone.txt	2020-01-01 12:00:00.000000000 +0000
++++ new/gone.txt	2020-01-02 12:00:00.000000000 +0000
+@@ -1,3 +1,2 @@
+ a
+-b
+ c
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	for i, file := range diff.Files {
+		expected := unified.Files[i]
+		assert.Equal(t, MODIFIED, file.Mode)
+		assert.Equal(t, expected.OrigName, file.OrigName)
+		assert.Equal(t, expected.NewName, file.NewName)
+		assert.Equal(t, expected.NewAnnotation, file.NewAnnotation)
+		require.Len(t, file.Hunks, len(expected.Hunks))
+		for j, h := range file.Hunks {
+			// The hunks are at different offsets in each diff.
+			assert.True(t, strings.HasPrefix(diff.Raw[h.RawStart:h.RawEnd], "***************\n"))
+			e := *expected.Hunks[j]
+			e.RawStart, e.RawEnd = h.RawStart, h.RawEnd
+			assert.Equal(t, &e, h)
+		}
+		assert.True(t, strings.HasPrefix(diff.Raw[file.RawStart:file.RawEnd], "*** "))
+	}
+	assert.NoError(t, diff.Files[0].CheckPositions())
+	assert.Equal(t, []int{3, 4, 6, 7, 22}, diff.Files[0].Changed())
+}
+
+func TestParseContextEmptySide(t *testing.T) {
+	diff, err := ParseContext(`*** /dev/null
+--- new.txt
+***************
+*** 0 ****
+--- 1,2 ----
++ one
++ two
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+
+	file := diff.Files[0]
+	assert.Equal(t, NEW, file.Mode)
+	assert.Equal(t, "new.txt", file.OrigName)
+	require.Len(t, file.Hunks, 1)
+	assert.Equal(t, "0,0", file.Hunks[0].OrigRange.String())
+	assert.Equal(t, "1,2", file.Hunks[0].NewRange.String())
+	assert.Equal(t, []int{1, 2}, file.Changed())
+}
+
+func TestParseContextNoNewline(t *testing.T) {
+	diff, err := ParseContext(`*** a/file	2020-01-01 12:00:00.000000000 +0000
+--- b/file	2020-01-02 12:00:00.000000000 +0000
+***************
+*** 1,2 ****
+  one
+! two
+\ No newline at end of file
+--- 1,2 ----
+  one
+! TWO
+\ No newline at end of file
+`)
+	require.NoError(t, err)
+	unified, err := Parse(`--- a/file	2020-01-01 12:00:00.000000000 +0000
++++ b/file	2020-01-02 12:00:00.000000000 +0000
+@@ -1,2 +1,2 @@
+ one
+-two
+\ No newline at end of file
++TWO
+\ No newline at end of file
+`)
+	require.NoError(t, err)
+
+	lines, expected := diff.Files[0].Hunks[0].WholeRange.Lines, unified.Files[0].Hunks[0].WholeRange.Lines
+	require.Len(t, lines, len(expected))
+	for i, l := range lines {
+		assert.Equal(t, *expected[i], *l)
+	}
+	assert.Equal(t, 4, lines[2].HunkPosition)
+	assert.NoError(t, diff.Files[0].CheckPositions())
+}
+
+func TestParseContextMismatch(t *testing.T) {
+	_, err := ParseContext(`*** a.txt
+--- b.txt
+***************
+*** 1,2 ****
+  one
+  two
+--- 1,3 ----
+  one
++ new
+`)
+	assert.Error(t, err)
+}
@@ -4,13 +4,25 @@
 package diffparser
 
 import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"fmt"
+	"io"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"errors"
 )
 
+// ErrRangeDiffUnsupported is returned when parsing the output of "git
+// range-diff", which compares two series of patches, rather than two
+// versions of some files.
+var ErrRangeDiffUnsupported = errors.New("range-diff output is not supported")
+
 // FileMode represents the file status in a diff
 type FileMode int
 
@@ -23,6 +35,8 @@ const (
 	NEW
 	// RENAMED if the file is renamed
 	RENAMED
+	// COPIED if the file is a copy of another file
+	COPIED
 )
 
 func (fm FileMode) String() string {
@@ -35,11 +49,43 @@ func (fm FileMode) String() string {
 		return "NEW"
 	case RENAMED:
 		return "RENAMED"
+	case COPIED:
+		return "COPIED"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// StatusLetter returns the letter git uses for the mode in the output of
+// commands such as "git status --short" and "git diff --name-status".
+func (fm FileMode) StatusLetter() string {
+	switch fm {
+	case DELETED:
+		return "D"
+	case MODIFIED:
+		return "M"
+	case NEW:
+		return "A"
+	case RENAMED:
+		return "R"
+	case COPIED:
+		return "C"
+	default:
+		return ""
+	}
+}
+
+// FileModeFromStatus returns the mode for one of git's status letters, as
+// returned by StatusLetter.
+func FileModeFromStatus(letter string) (FileMode, error) {
+	for _, fm := range []FileMode{DELETED, MODIFIED, NEW, RENAMED, COPIED} {
+		if fm.StatusLetter() == letter {
+			return fm, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown status letter %q", letter)
+}
+
 // DiffRange contains the DiffLine's
 type DiffRange struct {
 	// starting line number
@@ -83,6 +129,44 @@ type DiffLine struct {
 	Number   int
 	Content  string
 	Position int // the line in the diff
+
+	// OrigNumber and NewNumber are the numbers of the line in the original
+	// and new files, or zero if it isn't in that file. Unlike Number, they
+	// are the same for both copies of an unchanged line.
+	OrigNumber int
+	NewNumber  int
+
+	// HunkPosition is the line in the hunk, counting from one for the line
+	// after the "@@" header.
+	HunkPosition int
+
+	// NoNewline is set if the line is the last in its file, and doesn't end
+	// with a newline, which the diff shows with a "\ No newline at end of
+	// file" line after it.
+	NoNewline bool
+
+	// Collapsed is the number of unchanged lines the line stands in for,
+	// which is only set for the lines that Diff.Collapse puts in place of
+	// the lines it hides.
+	Collapsed int
+}
+
+// Side returns the side of the diff the line is on, as used by GitHub for
+// review comments: "LEFT" for removed lines, which are only in the original
+// file, or "RIGHT" for added and unchanged lines. The line number on that
+// side is OrigNumber or NewNumber respectively.
+func (l *DiffLine) Side() string {
+	if l.Mode == REMOVED {
+		return "LEFT"
+	}
+	return "RIGHT"
+}
+
+// TrimmedContent returns the line's content without leading or trailing
+// whitespace, and with every run of whitespace inside it replaced by a
+// single space, for comparing lines while ignoring changes to whitespace.
+func (l *DiffLine) TrimmedContent() string {
+	return strings.Join(strings.Fields(l.Content), " ")
 }
 
 // DiffHunk is a group of difflines
@@ -91,6 +175,12 @@ type DiffHunk struct {
 	OrigRange  DiffRange
 	NewRange   DiffRange
 	WholeRange DiffRange
+
+	// RawStart and RawEnd are the offsets in bytes of the start of the
+	// hunk's "@@" line and the end of its last line in the Raw of the diff
+	// it was parsed from.
+	RawStart int
+	RawEnd   int
 }
 
 // DiffFile is the sum of diffhunks and holds the changes of the file features
@@ -100,6 +190,60 @@ type DiffFile struct {
 	OrigName   string
 	NewName    string
 	Hunks      []*DiffHunk
+
+	// Binary is set if git reported the file as binary, either with a
+	// "Binary files differ" line or a "GIT binary patch".
+	Binary bool
+	// TextConv is a guess that the file is binary but was diffed as text
+	// through a textconv filter, because its extension is usually used for
+	// binary files but it has hunks. It is never set for Git LFS pointers.
+	TextConv bool
+	// LFS is set if the file is a Git LFS pointer, in which case OrigOID
+	// and NewOID are the object IDs it pointed to, such as "sha256:4d7a...",
+	// before and after the change.
+	LFS     bool
+	OrigOID string
+	NewOID  string
+
+	// OldMode and NewMode are the file's modes before and after a change to
+	// its mode, such as "100644" and "100755" when it's made executable.
+	// Deleted files only have an OldMode and new files only have a NewMode.
+	// Files from ParseRaw, or diffs with raw lines before them, always have
+	// both.
+	OldMode string
+	NewMode string
+
+	// OrigHash and NewHash are the, possibly abbreviated, IDs of the blobs
+	// for the file before and after the change, from its "index" line.
+	// IndexMode is the mode at the end of the line, which is only there if
+	// the mode didn't change.
+	OrigHash  string
+	NewHash   string
+	IndexMode string
+
+	// OrigAnnotation and NewAnnotation are whatever follows the names on
+	// the "---" and "+++" lines after a tab, such as the timestamps added by
+	// diff(1), or "(revision 123)" and "(working copy)" from Subversion.
+	OrigAnnotation string
+	NewAnnotation  string
+
+	// Commit is the commit that the file's changes are from, which is the
+	// Commit of the diff it was parsed from.
+	Commit string
+
+	// Similarity is the percentage of the file that's the same as the file
+	// it was renamed or copied from.
+	Similarity int
+
+	// RawStart and RawEnd are the offsets in bytes of the start of the
+	// file's first header line and the end of its last line in the Raw of
+	// the diff it was parsed from, which includes any lines after it that
+	// aren't part of the next file. They're both zero if the file wasn't
+	// parsed from Raw, such as the files from ParseRaw. For a file merged
+	// from several parts with Parser.AllowInterleaved, they span all of
+	// its parts, including any other files between them.
+	RawStart int
+	RawEnd   int
 }
 
 // Diff is the collection of DiffFiles
@@ -108,6 +252,22 @@ type Diff struct {
 	Raw   string `sql:"type:text"`
 
 	PullID uint `sql:"index"`
+
+	// Commit, Author and Message describe the commit that the diff is from,
+	// when it's parsed from the output of "git show".
+	Commit  string
+	Author  string
+	Message string
+
+	// PatchNumber and PatchTotal are the position of the patch in a series
+	// and the number of patches in it, from a subject like "[PATCH 2/5]"
+	// added by "git format-patch", or zero if there isn't one.
+	PatchNumber int
+	PatchTotal  int
+
+	// Trailer is any text after the diff, following a "-- " signature
+	// separator, like the one "git format-patch" adds.
+	Trailer string
 }
 
 // Changed returns a map of filename to lines changed in that file. Deleted
@@ -116,22 +276,347 @@ func (d *Diff) Changed() map[string][]int {
 	dFiles := make(map[string][]int)
 
 	for _, f := range d.Files {
-		if f.Mode == DELETED {
+		if lines := f.Changed(); len(lines) > 0 {
+			dFiles[f.NewName] = append(dFiles[f.NewName], lines...)
+		}
+	}
+
+	return dFiles
+}
+
+// Changed returns the numbers of the lines added to the file, which is empty
+// if the file was deleted.
+func (f *DiffFile) Changed() []int {
+	if f.Mode == DELETED {
+		return nil
+	}
+
+	var lines []int
+	for _, h := range f.Hunks {
+		for _, dl := range h.NewRange.Lines {
+			if dl.Mode == ADDED { // TODO(waigani) return removed
+				lines = append(lines, dl.Number)
+			}
+		}
+	}
+	return lines
+}
+
+// ChangedIncludingDeleted is like Changed, but also includes deleted files,
+// under their original name, with the numbers of their removed lines.
+func (d *Diff) ChangedIncludingDeleted() map[string][]int {
+	dFiles := d.Changed()
+
+	for _, f := range d.Files {
+		if f.Mode != DELETED {
 			continue
 		}
 
+		lines := []int{}
 		for _, h := range f.Hunks {
-			for _, dl := range h.NewRange.Lines {
-				if dl.Mode == ADDED { // TODO(waigani) return removed
-					dFiles[f.NewName] = append(dFiles[f.NewName], dl.Number)
+			for _, dl := range h.OrigRange.Lines {
+				if dl.Mode == REMOVED {
+					lines = append(lines, dl.Number)
 				}
 			}
 		}
+		dFiles[f.OrigName] = lines
 	}
 
 	return dFiles
 }
 
+// ChangedRanges is like Changed, but collapses consecutive line numbers into
+// inclusive [start, end] ranges.
+func (d *Diff) ChangedRanges() map[string][][2]int {
+	ranges := make(map[string][][2]int)
+
+	for name, lines := range d.Changed() {
+		for _, n := range lines {
+			rs := ranges[name]
+			if len(rs) > 0 && rs[len(rs)-1][1] == n-1 {
+				rs[len(rs)-1][1] = n
+				continue
+			}
+			ranges[name] = append(rs, [2]int{n, n})
+		}
+	}
+
+	return ranges
+}
+
+// ChangeBlock is a run of consecutive added lines in the new version of a
+// file.
+type ChangeBlock struct {
+	// Start and End are the numbers of the first and last added lines.
+	Start int
+	End   int
+
+	// Anchor is the number of the unchanged line before the block in its
+	// hunk, or zero if the hunk starts with the block.
+	Anchor int
+}
+
+// ChangedWithContextAnchors is like ChangedRanges, but with each block of
+// added lines anchored to the unchanged line before it, which can be found
+// even after the added lines have moved.
+func (d *Diff) ChangedWithContextAnchors() map[string][]ChangeBlock {
+	blocks := make(map[string][]ChangeBlock)
+
+	for _, f := range d.Files {
+		if f.Mode == DELETED {
+			continue
+		}
+		for _, h := range f.Hunks {
+			anchor := 0
+			inBlock := false
+			for _, l := range h.NewRange.Lines {
+				if l.Mode != ADDED {
+					anchor = l.Number
+					inBlock = false
+					continue
+				}
+				bs := blocks[f.NewName]
+				if inBlock {
+					bs[len(bs)-1].End = l.Number
+					continue
+				}
+				blocks[f.NewName] = append(bs, ChangeBlock{Start: l.Number, End: l.Number, Anchor: anchor})
+				inBlock = true
+			}
+		}
+	}
+
+	return blocks
+}
+
+// WalkFiles calls fn for each file in the diff, in order, stopping at the
+// first error it returns, which is returned by WalkFiles.
+func (d *Diff) WalkFiles(fn func(*DiffFile) error) error {
+	for _, f := range d.Files {
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForEachChangedLine calls fn for each added line in the diff, along with the
+// file it's in.
+func (d *Diff) ForEachChangedLine(fn func(file *DiffFile, line *DiffLine)) {
+	d.forEachLine(fn, ADDED)
+}
+
+// ForEachChangedLineIncludingRemoved is like ForEachChangedLine, but also
+// calls fn for each removed line, in the order they appear in the diff.
+func (d *Diff) ForEachChangedLineIncludingRemoved(fn func(file *DiffFile, line *DiffLine)) {
+	d.forEachLine(fn, ADDED, REMOVED)
+}
+
+func (d *Diff) forEachLine(fn func(file *DiffFile, line *DiffLine), modes ...DiffLineMode) {
+	for _, f := range d.Files {
+		for _, h := range f.Hunks {
+			for _, dl := range h.WholeRange.Lines {
+				for _, m := range modes {
+					if dl.Mode == m {
+						fn(f, dl)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// Len returns the number of files in the diff.
+func (d *Diff) Len() int {
+	return len(d.Files)
+}
+
+// HunkCount returns the number of hunks in all the files in the diff.
+func (d *Diff) HunkCount() int {
+	n := 0
+	for _, f := range d.Files {
+		n += f.HunkCount()
+	}
+	return n
+}
+
+// LineCount returns the number of lines in the bodies of all the hunks in
+// the diff, as counted by DiffHunk.LineCount.
+func (d *Diff) LineCount() int {
+	n := 0
+	for _, f := range d.Files {
+		for _, h := range f.Hunks {
+			n += h.LineCount()
+		}
+	}
+	return n
+}
+
+// IsEmpty returns whether none of the files in the diff have any added or
+// removed lines.
+func (d *Diff) IsEmpty() bool {
+	for _, f := range d.Files {
+		if f.HasChanges() {
+			return false
+		}
+	}
+	return true
+}
+
+// MostChangedFiles returns up to n of the files in the diff with the most
+// added and removed lines, from the most to the least, and in order of their
+// names when they have the same number.
+func (d *Diff) MostChangedFiles(n int) []*DiffFile {
+	changed := make(map[*DiffFile]int, len(d.Files))
+	for _, f := range d.Files {
+		for _, h := range f.Hunks {
+			for _, l := range h.WholeRange.Lines {
+				if l.Mode == ADDED || l.Mode == REMOVED {
+					changed[f]++
+				}
+			}
+		}
+	}
+
+	files := append([]*DiffFile(nil), d.Files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		if changed[files[i]] != changed[files[j]] {
+			return changed[files[i]] > changed[files[j]]
+		}
+		return files[i].name() < files[j].name()
+	})
+	if n < 0 {
+		n = 0
+	}
+	if len(files) > n {
+		files = files[:n]
+	}
+	return files
+}
+
+// StructEqual returns whether the diff has the same files as other, with
+// the same hunks and lines. Only the parsed structure is compared, so the
+// Position of the lines, the Raw diff, the DiffHeader of the files and the
+// commit details are ignored.
+func (d *Diff) StructEqual(other *Diff) bool {
+	if len(d.Files) != len(other.Files) {
+		return false
+	}
+	for i, f := range d.Files {
+		if !f.structEqual(other.Files[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *DiffFile) structEqual(other *DiffFile) bool {
+	if f.Mode != other.Mode || f.OrigName != other.OrigName || f.NewName != other.NewName ||
+		f.Binary != other.Binary || f.OldMode != other.OldMode || f.NewMode != other.NewMode {
+		return false
+	}
+	if len(f.Hunks) != len(other.Hunks) {
+		return false
+	}
+	for i, h := range f.Hunks {
+		o := other.Hunks[i]
+		if h.HunkHeader != o.HunkHeader ||
+			h.OrigRange.Start != o.OrigRange.Start || h.OrigRange.Length != o.OrigRange.Length ||
+			h.NewRange.Start != o.NewRange.Start || h.NewRange.Length != o.NewRange.Length {
+			return false
+		}
+		if len(h.WholeRange.Lines) != len(o.WholeRange.Lines) {
+			return false
+		}
+		for j, l := range h.WholeRange.Lines {
+			ol := o.WholeRange.Lines[j]
+			if l.Mode != ol.Mode || l.Content != ol.Content || l.NoNewline != ol.NoNewline ||
+				l.Number != ol.Number || l.OrigNumber != ol.OrigNumber || l.NewNumber != ol.NewNumber {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// File returns the file in the diff with the new or original name, or nil
+// if there isn't one.
+func (d *Diff) File(name string) *DiffFile {
+	for _, f := range d.Files {
+		if f.NewName == name || f.OrigName == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// ContainsPath returns whether the diff has a file with the new or original
+// name, such as to check if a particular file was changed.
+func (d *Diff) ContainsPath(name string) bool {
+	return d.File(name) != nil
+}
+
+// RemoveFile removes the file with the new or original name from the diff,
+// and returns whether there was one. Raw is left as it was, so still has
+// the removed file in it.
+func (d *Diff) RemoveFile(name string) bool {
+	for i, f := range d.Files {
+		if f.NewName == name || f.OrigName == name {
+			d.Files = append(d.Files[:i], d.Files[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the hunks of each file in the diff are in order and
+// don't overlap, as in the diffs git writes, which catches some corrupted or
+// badly edited diffs. It returns an error describing the first problem.
+func (d *Diff) Validate() error {
+	for _, f := range d.Files {
+		for i := 1; i < len(f.Hunks); i++ {
+			prev, next := f.Hunks[i-1].NewRange, f.Hunks[i].NewRange
+			end := prev.first() + prev.Length - 1
+			if next.first() <= end {
+				return fmt.Errorf("%s: hunk %d starts at line %d, which is before the end of the hunk before it at line %d", f.name(), i+1, next.first(), end)
+			}
+		}
+	}
+	return nil
+}
+
+// RenumberPositions sets the Position and HunkPosition of every line in the
+// diff, so that they follow GitHub's rules again after changing the hunks
+// or their lines.
+func (d *Diff) RenumberPositions() {
+	for _, f := range d.Files {
+		f.renumberPositions()
+	}
+}
+
+// HunkGaps returns the inclusive [start, end] ranges of lines in the new
+// version of the named file that are between its hunks, so aren't in the
+// diff at all.
+func (d *Diff) HunkGaps(name string) [][2]int {
+	f := d.File(name)
+	if f == nil {
+		return nil
+	}
+
+	var gaps [][2]int
+	for i := 1; i < len(f.Hunks); i++ {
+		prev, next := f.Hunks[i-1].NewRange, f.Hunks[i].NewRange
+		start := prev.first() + prev.Length
+		end := next.first() - 1
+		if start <= end {
+			gaps = append(gaps, [2]int{start, end})
+		}
+	}
+	return gaps
+}
+
 func lineMode(line string) (*DiffLineMode, error) {
 	var m DiffLineMode
 	switch line[:1] {
@@ -147,179 +632,910 @@ func lineMode(line string) (*DiffLineMode, error) {
 	return &m, nil
 }
 
+// DefaultMaxLineBytes is the longest line that ParseReader and the other
+// functions reading from an io.Reader will read, unless Parser.MaxLineBytes
+// says otherwise.
+const DefaultMaxLineBytes = 64 * 1024 * 1024
+
+// Parser parses diffs. The zero value is ready to use, and parses diffs the
+// same way as the package-level Parse and ParseReader functions.
+type Parser struct {
+	// MaxLineBytes is the longest line that ParseReader, ScanLines and
+	// ParseLog will read, which can be raised for diffs of minified or
+	// generated files. If zero, DefaultMaxLineBytes is used.
+	MaxLineBytes int
+
+	// SingleContextLine makes unchanged lines share a single DiffLine
+	// between the OrigRange, NewRange and WholeRange of their hunk, instead
+	// of having a separate copy in OrigRange. Its Number is the number in
+	// the new file.
+	SingleContextLine bool
+
+	// AllowInterleaved allows the hunks for a file to be split between
+	// several parts of the diff, each with their own "diff" line, which are
+	// merged into a single DiffFile. git never does this, but other tools
+	// might.
+	AllowInterleaved bool
+
+	// IncludePaths limits the files whose hunks are parsed to those with a
+	// new or original name in the list, if it isn't empty. Other files are
+	// still in the diff, but without any hunks.
+	IncludePaths []string
+
+	// IgnorePrefixes lists prefixes of lines to skip, such as "# " for log
+	// lines mixed in with a diff. Lines in hunks are never skipped.
+	IgnorePrefixes []string
+
+	// Lenient accepts some mistakes made by broken tools, instead of
+	// returning an error. Lines in hunks starting with a tab are taken to
+	// be unchanged, with the tab kept in their Content.
+	Lenient bool
+
+	// RejectNUL returns an error for lines in hunks that contain a NUL
+	// byte, which usually means that binary data got into the diff, such as
+	// from a textconv filter.
+	RejectNUL bool
+}
+
 // Parse takes a diff, such as produced by "git diff", and parses it into a
 // Diff struct.
+//
+// Each hunk ends once it has as many lines as its header says. Any lines
+// after that which look like part of it, such as an extra "+" line that the
+// header doesn't count, aren't part of the diff, and are ignored like other
+// text between files.
 func Parse(diffString string) (*Diff, error) {
-	var diff Diff
-	diff.Raw = diffString
-	lines := strings.Split(diffString, "\n")
-
-	var file *DiffFile
-	var hunk *DiffHunk
-	var ADDEDCount int
-	var REMOVEDCount int
-	var inHunk bool
-
-	var diffPosCount int
-	var firstHunkInFile bool
-	// Parse each line of diff.
-	for idx, l := range lines {
-		diffPosCount++
-		switch {
-		case strings.HasPrefix(l, "diff "):
-			inHunk = false
-			firstHunkInFile = true
+	return (&Parser{}).Parse(diffString)
+}
 
-			// Start a new file.
-			file = &DiffFile{
-				Mode: MODIFIED, // default is modified
-			}
-			diff.Files = append(diff.Files, file)
+// ParseReader is like Parse, but reads the diff from r.
+func ParseReader(r io.Reader) (*Diff, error) {
+	return (&Parser{}).ParseReader(r)
+}
 
-			// Parse the filenames from the diff line.
-			if fields := strings.Fields(l); len(fields) >= 3 {
-				from, to := fields[len(fields)-2], fields[len(fields)-1]
-				if original, ok := strings.CutPrefix(from, "a/"); ok {
-					file.OrigName = original
-				}
-				if updated, ok := strings.CutPrefix(to, "b/"); ok {
-					file.NewName = updated
-				}
+// ParseGzip is like ParseReader, but for a diff compressed with gzip.
+func ParseGzip(r io.Reader) (*Diff, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ParseReader(zr)
+}
+
+// Parse takes a diff, such as produced by "git diff", and parses it into a
+// Diff struct. Like the package-level Parse, it ignores any lines after a
+// hunk has as many as its header says.
+func (p *Parser) Parse(diffString string) (*Diff, error) {
+	state := newParser(p)
+	state.diff.Raw = diffString
+	for _, l := range strings.Split(diffString, "\n") {
+		if err := state.parseLine(l); err != nil {
+			return nil, err
+		}
+	}
+	return state.finish(), nil
+}
+
+// ParseReader is like Parse, but reads the diff from r.
+func (p *Parser) ParseReader(r io.Reader) (*Diff, error) {
+	state := newParser(p)
+	var raw strings.Builder
+	err := p.readLines(r, func(line, token string) error {
+		raw.WriteString(token)
+		return state.parseLine(line)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state.diff.Raw = raw.String()
+	return state.finish(), nil
+}
+
+// readLines reads r a line at a time, up to MaxLineBytes long, calling fn
+// with each line and the token it was read from, which still has its
+// newline. Like Parse, it sees an empty line with an empty token after a
+// trailing newline.
+func (p *Parser) readLines(r io.Reader, fn func(line, token string) error) error {
+	maxLineBytes := p.MaxLineBytes
+	if maxLineBytes <= 0 {
+		maxLineBytes = DefaultMaxLineBytes
+	}
+
+	scanner := bufio.NewScanner(r)
+	// Leave room for the newline, which is part of each token.
+	scanner.Buffer(nil, maxLineBytes+1)
+	scanner.Split(scanRawLines)
+
+	lineNo := 0
+	terminated := true
+	for scanner.Scan() {
+		lineNo++
+		token := scanner.Text()
+		terminated = strings.HasSuffix(token, "\n")
+		if err := fn(strings.TrimSuffix(token, "\n"), token); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("line %d is longer than the maximum of %d bytes", lineNo+1, maxLineBytes)
+		}
+		return err
+	}
+	if terminated {
+		return fn("", "")
+	}
+	return nil
+}
+
+// scanRawLines is a bufio.SplitFunc like bufio.ScanLines, except that it
+// keeps line endings intact, so the input can be reconstructed exactly.
+func scanRawLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// parser holds the state of a diff as it is parsed, one line at a time.
+type parser struct {
+	*Parser
+
+	diff *Diff
+	file *DiffFile
+	hunk *DiffHunk
+
+	// lineNo is the number of the current line in the whole diff, and
+	// lineStart and lineEnd are the offsets of its start and the end of its
+	// newline.
+	lineNo    int
+	lineStart int
+	lineEnd   int
+
+	addedCount   int
+	removedCount int
+	inHunk       bool
+	// skipHunk is set when the current hunk is for a file that isn't in
+	// IncludePaths, so its lines aren't kept.
+	skipHunk bool
+	// inBinary is set while reading the contents of a binary file, after a
+	// "GIT binary patch" line.
+	inBinary bool
+	// modeSet is set when a header line has said what happened to the
+	// current file, such as that it was renamed or copied.
+	modeSet bool
+	// gitDiff is set when the current file started with a "diff --git"
+	// line, so different names on each side mean it was renamed.
+	gitDiff bool
+
+	diffPosCount    int
+	hunkPosCount    int
+	firstHunkInFile bool
+	// resumeFile is set when adding more hunks to a file from earlier in
+	// the diff, to continue from its positions.
+	resumeFile bool
+
+	// inHeader is set from the start of a file until its first hunk, while
+	// header lines are added to the file's DiffHeader.
+	inHeader bool
+	// pendingHeader holds extended header lines seen before the start of
+	// the file they belong to, the first of which starts at pendingStart.
+	pendingHeader []string
+	pendingStart  int
+	// modeChanges holds the old and new modes from "mode change" summary
+	// lines, by path, to be added to the files when we're done.
+	modeChanges     map[string][2]string
+	modeChangePaths []string
+	// rawFiles holds the files from any "--raw" lines before the diff, to
+	// add their details to the files when we're done.
+	rawFiles []*DiffFile
+
+	// trailerLines counts the lines since the start of the trailer, or is
+	// zero if it hasn't started.
+	trailerLines int
+}
+
+func newParser(p *Parser) *parser {
+	return &parser{
+		Parser: p,
+		diff:   &Diff{},
+	}
+}
+
+var (
+	reHunkHeader = regexp.MustCompile(`@@ *\-(\d+),?(\d+)? \+(\d+),?(\d+)? @@ ?(.+)?`)
+)
+
+// ParseHunkHeader parses the "@@" line at the start of a hunk, such as
+// "@@ -10,5 +12,6 @@ func main() {", into the ranges of lines from the
+// original and new files, and the section heading after the second "@@",
+// which is empty if there isn't one. A range without a length, like "-10",
+// has one line.
+func ParseHunkHeader(line string) (origRange, newRange DiffRange, section string, err error) {
+	m := reHunkHeader.FindStringSubmatch(line)
+	if len(m) < 5 {
+		return DiffRange{}, DiffRange{}, "", errors.New("Error parsing line: " + line)
+	}
+	if origRange, err = parseHunkRange(m[1], m[2]); err != nil {
+		return DiffRange{}, DiffRange{}, "", err
+	}
+	if newRange, err = parseHunkRange(m[3], m[4]); err != nil {
+		return DiffRange{}, DiffRange{}, "", err
+	}
+	return origRange, newRange, m[5], nil
+}
+
+// parseHunkRange parses the start and optional length of a range from a
+// hunk header.
+func parseHunkRange(start, length string) (DiffRange, error) {
+	r := DiffRange{Length: 1}
+	var err error
+	if r.Start, err = strconv.Atoi(start); err != nil {
+		return DiffRange{}, err
+	}
+	if length != "" {
+		if r.Length, err = strconv.Atoi(length); err != nil {
+			return DiffRange{}, err
+		}
+	}
+	// Lines are numbered from one, so only an empty range can start at
+	// zero, but some broken tools write them for non-empty ones.
+	if r.Start == 0 && r.Length > 0 {
+		r.Start = 1
+	}
+	return r, nil
+}
+
+// parseLine parses the next line of the diff.
+func (p *parser) parseLine(l string) error {
+	p.lineNo++
+	p.lineStart, p.lineEnd = p.lineEnd, p.lineEnd+len(l)+1
+	if !p.inHunk {
+		for _, prefix := range p.IgnorePrefixes {
+			if strings.HasPrefix(l, prefix) {
+				return nil
 			}
+		}
+	}
 
-			header := l
-			if len(lines) > idx+3 {
-				// FIXME(jedevc): this logic is pretty much entirely broken
-				rein := regexp.MustCompile(`^index .+$`)
-				remp := regexp.MustCompile(`^(-|\+){3} .+$`)
-				index := lines[idx+1]
-				if rein.MatchString(index) {
-					header = header + "\n" + index
-				}
-				mp1 := lines[idx+2]
-				mp2 := lines[idx+3]
-				if remp.MatchString(mp1) && remp.MatchString(mp2) {
-					header = header + "\n" + mp1 + "\n" + mp2
-				}
+	p.diffPosCount++
+	p.hunkPosCount++
+
+	// Everything after the signature separator is part of the trailer.
+	if p.trailerLines > 0 {
+		if p.trailerLines > 1 {
+			p.diff.Trailer += "\n"
+		}
+		p.diff.Trailer += l
+		p.trailerLines++
+		return nil
+	}
+	if p.file != nil && !p.inHunk && l == "-- " {
+		p.trailerLines = 1
+		return nil
+	}
+
+	// The encoded contents of a binary file run until the next file.
+	if p.inBinary {
+		if !strings.HasPrefix(l, "diff ") {
+			p.file.RawEnd = p.lineEnd
+			return nil
+		}
+		p.inBinary = false
+	}
+
+	// Extended header lines usually follow the "diff" line, but some tools
+	// put them first, so hold on to any we see outside of a file's header
+	// until the next file starts.
+	if !p.inHeader && !p.inHunk && isExtendedHeader(l) && (p.file != nil || reStrictExtendedHeader.MatchString(l)) {
+		if len(p.pendingHeader) == 0 {
+			p.pendingStart = p.lineStart
+		}
+		p.pendingHeader = append(p.pendingHeader, l)
+		return nil
+	}
+
+	// Anything before the first file, such as the table printed by "git diff
+	// --stat --patch", isn't part of the diff.
+	if p.file == nil && !strings.HasPrefix(l, "diff ") && !strings.HasPrefix(l, "--- ") && !reBazaarHeader.MatchString(l) {
+		// Header lines are only followed by more of the file, so any
+		// before this were really part of the preamble, such as a commit
+		// message.
+		p.pendingHeader = nil
+		return p.parsePreamble(l)
+	}
+
+	switch {
+	case strings.HasPrefix(l, "diff "):
+		p.startFile(l)
+
+		// Parse the filenames from the diff line.
+		fields := strings.Fields(l)
+		p.gitDiff = len(fields) >= 2 && fields[1] == "--git"
+		switch {
+		case len(fields) >= 3 && fields[1] == "-r":
+			// Mercurial diffs name the revisions being compared, then the
+			// path, which is the same on both sides.
+			p.file.OrigName = fields[len(fields)-1]
+			p.file.NewName = fields[len(fields)-1]
+		case len(fields) >= 3:
+			// The names are usually prefixed with "a/" and "b/", but not
+			// with --no-prefix, or in other tools' diffs.
+			from, to := fields[len(fields)-2], fields[len(fields)-1]
+			if hasNamePrefix(from, 'a') && hasNamePrefix(to, 'b') {
+				from, to = from[2:], to[2:]
+			}
+			p.file.OrigName = from
+			p.file.NewName = to
+		}
+		if p.AllowInterleaved {
+			p.mergeRepeatedFile()
+		}
+	case !p.inHunk && strings.HasPrefix(l, "=== "):
+		// Bazaar starts each file with a line saying what happened to it.
+		m := reBazaarHeader.FindStringSubmatch(l)
+		if m == nil {
+			break
+		}
+		p.startFile(l)
+		p.modeSet = true
+		p.file.OrigName, p.file.NewName = m[2], m[2]
+		switch m[1] {
+		case "added":
+			p.file.Mode = NEW
+		case "removed":
+			p.file.Mode = DELETED
+		case "renamed":
+			p.file.Mode = RENAMED
+			if m[3] != "" {
+				p.file.NewName = m[3]
 			}
-			file.DiffHeader = header
-		case strings.HasPrefix(l, "deleted file "):
-			file.Mode = DELETED
-		case strings.HasPrefix(l, "new file "):
-			file.Mode = NEW
-		case strings.HasPrefix(l, "rename "):
-			file.Mode = RENAMED
-		case strings.HasPrefix(l, "@@ "):
-			if firstHunkInFile {
-				diffPosCount = 0
-				firstHunkInFile = false
+		}
+	case !p.inHunk && strings.HasPrefix(l, "--- "):
+		// Without a "diff" line, as in plain unified diffs, the "---" line
+		// is the start of the file.
+		if p.file == nil || p.hunk != nil {
+			p.startFile(l)
+		} else if p.inHeader {
+			p.file.DiffHeader += "\n" + l
+		}
+		name, annotation, ok := headerName(l[4:], 'a')
+		p.file.OrigAnnotation = annotation
+		if ok {
+			p.file.OrigName = name
+		} else {
+			p.file.Mode = NEW
+		}
+	case !p.inHunk && p.file != nil && strings.HasPrefix(l, "+++ "):
+		if p.inHeader {
+			p.file.DiffHeader += "\n" + l
+		}
+		// Added and deleted files use the one name they have on both sides.
+		name, annotation, ok := headerName(l[4:], 'b')
+		p.file.NewAnnotation = annotation
+		if ok {
+			p.file.NewName = name
+			switch {
+			case p.file.Mode == NEW:
+				p.file.OrigName = name
+			case p.gitDiff && !p.modeSet && p.file.OrigName != "" && p.file.OrigName != name:
+				// Without any headers saying otherwise, different names
+				// on each side of a git diff mean the file was renamed.
+				// diff(1) compares files with different names, such as
+				// "file.orig" and "file", without renaming anything.
+				p.file.Mode = RENAMED
 			}
+		} else {
+			p.file.Mode = DELETED
+			p.file.NewName = p.file.OrigName
+		}
+	case p.inHeader && isExtendedHeader(l):
+		p.file.DiffHeader += "\n" + l
+		p.parseExtendedHeader(l)
+	case strings.HasPrefix(l, "Binary files "):
+		p.file.Binary = true
+	case strings.TrimSuffix(l, "\r") == "GIT binary patch":
+		p.file.Binary = true
+		p.inBinary = true
+	case strings.HasPrefix(l, "@@@"):
+		// The hunks of combined diffs, from "git diff --cc", compare more
+		// than two versions of the file, which a DiffHunk can't hold, so
+		// they're skipped.
+		p.inHeader = false
+		p.inHunk = false
+		p.hunk = nil
+	case strings.HasPrefix(l, "@@"):
+		// The space after the "@@" is sometimes missing from hand-edited
+		// diffs.
+		if p.firstHunkInFile {
+			p.diffPosCount = 0
+			p.firstHunkInFile = false
+		}
+		if p.resumeFile {
+			p.diffPosCount = lastPosition(p.file) + 1
+			p.resumeFile = false
+		}
+
+		p.inHeader = false
+		p.inHunk = true
+		p.hunkPosCount = 0
+		// Start new hunk.
+		p.hunk = &DiffHunk{RawStart: p.lineStart, RawEnd: p.lineEnd}
+		p.skipHunk = !p.included(p.file)
+		if !p.skipHunk {
+			p.file.Hunks = append(p.file.Hunks, p.hunk)
+		}
 
-			inHunk = true
-			// Start new hunk.
-			hunk = &DiffHunk{}
-			file.Hunks = append(file.Hunks, hunk)
+		origRange, newRange, section, err := ParseHunkHeader(l)
+		if err != nil {
+			return err
+		}
+		p.hunk.OrigRange = origRange
+		p.hunk.NewRange = newRange
+		p.hunk.HunkHeader = section
 
-			// Parse hunk heading for ranges
-			re := regexp.MustCompile(`@@ \-(\d+),?(\d+)? \+(\d+),?(\d+)? @@ ?(.+)?`)
-			m := re.FindStringSubmatch(l)
-			if len(m) < 5 {
-				return nil, errors.New("Error parsing line: " + l)
+		// (re)set line counts
+		p.addedCount = p.hunk.NewRange.Start
+		p.removedCount = p.hunk.OrigRange.Start
+		p.inHunk = !p.hunkDone()
+	case p.hunk != nil && strings.HasPrefix(l, "\\ "):
+		// The marker for a missing newline applies to the line before it,
+		// on both sides if it was unchanged.
+		p.hunk.RawEnd = p.lineEnd
+		if n := len(p.hunk.WholeRange.Lines); n > 0 {
+			last := p.hunk.WholeRange.Lines[n-1]
+			last.NoNewline = true
+			if m := len(p.hunk.OrigRange.Lines); last.Mode == UNCHANGED && m > 0 {
+				p.hunk.OrigRange.Lines[m-1].NoNewline = true
 			}
-			a, err := strconv.Atoi(m[1])
+		}
+	case p.inHunk && isSourceLine(l):
+		if p.RejectNUL && strings.IndexByte(l, 0) >= 0 {
+			return fmt.Errorf("line %d contains a NUL byte", p.lineNo)
+		}
+		p.hunk.RawEnd = p.lineEnd
+		var mode DiffLineMode
+		content := l[1:]
+		if p.Lenient && l[0] == '\t' {
+			// Some broken tools indent unchanged lines with a tab instead
+			// of a space, which we keep as part of the content.
+			mode, content = UNCHANGED, l
+		} else {
+			m, err := lineMode(l)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			b := a
-			if len(m[2]) > 0 {
-				b, err = strconv.Atoi(m[2])
-				if err != nil {
-					return nil, err
-				}
+			mode = *m
+		}
+		if p.skipHunk {
+			// Only count the lines, to find the end of the hunk.
+			if mode != ADDED {
+				p.removedCount++
 			}
-			c, err := strconv.Atoi(m[3])
-			if err != nil {
-				return nil, err
+			if mode != REMOVED {
+				p.addedCount++
 			}
-			d := c
-			if len(m[4]) > 0 {
-				d, err = strconv.Atoi(m[4])
-				if err != nil {
-					return nil, err
-				}
+			p.inHunk = !p.hunkDone()
+			break
+		}
+		line := DiffLine{
+			Mode:     mode,
+			Content:  content,
+			Position: p.diffPosCount,
+
+			HunkPosition: p.hunkPosCount,
+		}
+		p.hunk.addLine(line, &p.removedCount, &p.addedCount, p.SingleContextLine)
+		p.inHunk = !p.hunkDone()
+	}
+
+	if p.file != nil {
+		p.file.RawEnd = p.lineEnd
+	}
+	return nil
+}
+
+var reBazaarHeader = regexp.MustCompile(`^=== (added|modified|removed|renamed) file '(.+?)'(?: => '(.+?)')?( .*)?$`)
+
+var reSubjectSeries = regexp.MustCompile(`^Subject: \[[^\]]*?(\d+)/(\d+)\]`)
+
+var reModeChange = regexp.MustCompile(`^ ?mode change (\d+) => (\d+) (.+)$`)
+
+var reRangeDiff = regexp.MustCompile(`^(\d+|-):\s+([0-9a-f]+|-+) [=!<>] +(\d+|-):\s+([0-9a-f]+|-+)( |$)`)
+
+// parsePreamble parses a line from before the first file in the diff, which
+// may describe the commit it's from.
+func (p *parser) parsePreamble(l string) error {
+	switch {
+	case reRangeDiff.MatchString(l):
+		return ErrRangeDiffUnsupported
+	case reModeChange.MatchString(l):
+		m := reModeChange.FindStringSubmatch(l)
+		if p.modeChanges == nil {
+			p.modeChanges = make(map[string][2]string)
+		}
+		if _, ok := p.modeChanges[m[3]]; !ok {
+			p.modeChangePaths = append(p.modeChangePaths, m[3])
+		}
+		p.modeChanges[m[3]] = [2]string{m[1], m[2]}
+	case strings.HasPrefix(l, ":"):
+		// Lines like the output of "git diff --raw", from --patch-with-raw.
+		if f, err := parseRawLine(l); err == nil {
+			p.rawFiles = append(p.rawFiles, f)
+		}
+	case reSubjectSeries.MatchString(l):
+		m := reSubjectSeries.FindStringSubmatch(l)
+		p.diff.PatchNumber, _ = strconv.Atoi(m[1])
+		p.diff.PatchTotal, _ = strconv.Atoi(m[2])
+	case strings.HasPrefix(l, "commit "):
+		if fields := strings.Fields(l); len(fields) >= 2 {
+			p.diff.Commit = fields[1]
+		}
+	case p.diff.Commit == "":
+		// Without a commit, this is some other preamble, such as the
+		// table from "git diff --stat".
+	case strings.HasPrefix(l, "Author:"):
+		p.diff.Author = strings.TrimSpace(l[len("Author:"):])
+	case strings.HasPrefix(l, "    "):
+		// The message is indented, including its blank lines.
+		if p.diff.Message != "" {
+			p.diff.Message += "\n"
+		}
+		p.diff.Message += l[4:]
+	}
+	return nil
+}
+
+// finish finishes parsing, and returns the parsed diff.
+func (p *parser) finish() *Diff {
+	// Add the modes from any "mode change" lines to the files they're for,
+	// which are added if they aren't in the diff already.
+	for _, path := range p.modeChangePaths {
+		modes := p.modeChanges[path]
+		found := false
+		for _, f := range p.diff.Files {
+			if f.NewName != path {
+				continue
 			}
-			if len(m[5]) > 0 {
-				hunk.HunkHeader = m[5]
+			found = true
+			if f.OldMode == "" && f.NewMode == "" {
+				f.OldMode, f.NewMode = modes[0], modes[1]
 			}
+		}
+		if !found {
+			p.diff.Files = append(p.diff.Files, &DiffFile{
+				Mode:     MODIFIED,
+				OrigName: path,
+				NewName:  path,
+				OldMode:  modes[0],
+				NewMode:  modes[1],
+			})
+		}
+	}
 
-			// hunk orig range.
-			hunk.OrigRange = DiffRange{
-				Start:  a,
-				Length: b,
+	// Fill in anything missing from the files with the details from their
+	// raw lines, adding any files that aren't in the diff.
+	for _, raw := range p.rawFiles {
+		var file *DiffFile
+		for _, f := range p.diff.Files {
+			if f.NewName == raw.NewName && f.OrigName == raw.OrigName {
+				file = f
+				break
 			}
+		}
+		if file == nil {
+			p.diff.Files = append(p.diff.Files, raw)
+			continue
+		}
+		file.OldMode = cmp.Or(file.OldMode, raw.OldMode)
+		file.NewMode = cmp.Or(file.NewMode, raw.NewMode)
+		file.OrigHash = cmp.Or(file.OrigHash, raw.OrigHash)
+		file.NewHash = cmp.Or(file.NewHash, raw.NewHash)
+		file.Similarity = cmp.Or(file.Similarity, raw.Similarity)
+	}
 
-			// hunk new range.
-			hunk.NewRange = DiffRange{
-				Start:  c,
-				Length: d,
-			}
+	for _, f := range p.diff.Files {
+		f.detectLFS()
+		// LFS pointers are text, whatever the file they point to is.
+		f.TextConv = !f.Binary && !f.LFS && len(f.Hunks) > 0 && binaryExtensions[strings.ToLower(f.Extension())]
+		f.Commit = p.diff.Commit
 
-			// (re)set line counts
-			ADDEDCount = hunk.NewRange.Start
-			REMOVEDCount = hunk.OrigRange.Start
-		case inHunk && isSourceLine(l):
-			m, err := lineMode(l)
-			if err != nil {
-				return nil, err
-			}
-			line := DiffLine{
-				Mode:     *m,
-				Content:  l[1:],
-				Position: diffPosCount,
+		// The last line of the diff doesn't end with a newline.
+		if f.RawEnd > len(p.diff.Raw) {
+			f.RawEnd = len(p.diff.Raw)
+		}
+		for _, h := range f.Hunks {
+			if h.RawEnd > len(p.diff.Raw) {
+				h.RawEnd = len(p.diff.Raw)
 			}
-			newLine := line
-			origLine := line
-
-			// add lines to ranges
-			switch *m {
-			case ADDED:
-				newLine.Number = ADDEDCount
-				hunk.NewRange.Lines = append(hunk.NewRange.Lines, &newLine)
-				hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &newLine)
-				ADDEDCount++
-
-			case REMOVED:
-				origLine.Number = REMOVEDCount
-				hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, &origLine)
-				hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &origLine)
-				REMOVEDCount++
-
-			case UNCHANGED:
-				newLine.Number = ADDEDCount
-				hunk.NewRange.Lines = append(hunk.NewRange.Lines, &newLine)
-				hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &newLine)
-				origLine.Number = REMOVEDCount
-				hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, &origLine)
-				ADDEDCount++
-				REMOVEDCount++
+		}
+	}
+
+	return p.diff
+}
+
+// startFile starts a new file in the diff, beginning with the header line l.
+func (p *parser) startFile(l string) {
+	p.hunk = nil
+	p.inHunk = false
+	p.firstHunkInFile = true
+	p.resumeFile = false
+	p.inHeader = true
+	p.inBinary = false
+	p.modeSet = false
+	p.gitDiff = false
+
+	// Any extended header lines we've already seen belong to this file.
+	pending := p.pendingHeader
+	p.pendingHeader = nil
+
+	p.file = &DiffFile{
+		Mode:       MODIFIED, // default is modified
+		DiffHeader: strings.Join(append(pending, l), "\n"),
+		RawStart:   p.lineStart,
+	}
+	if len(pending) > 0 {
+		p.file.RawStart = p.pendingStart
+	}
+	p.diff.Files = append(p.diff.Files, p.file)
+	for _, h := range pending {
+		p.parseExtendedHeader(h)
+	}
+}
+
+// extendedHeaders are the prefixes of the lines git writes between the
+// "diff" line and the "---" and "+++" lines.
+var extendedHeaders = []string{
+	"old mode ",
+	"new mode ",
+	"deleted file mode ",
+	"new file mode ",
+	"copy from ",
+	"copy to ",
+	"rename from ",
+	"rename to ",
+	"rename old ",
+	"rename new ",
+	"similarity index ",
+	"dissimilarity index ",
+	"index ",
+}
+
+// reStrictExtendedHeader matches the extended header lines git writes
+// exactly, to tell them apart from text that happens to start the same way
+// before the first file, such as in a commit message.
+var reStrictExtendedHeader = regexp.MustCompile(`^(?:index [0-9a-f]+\.\.[0-9a-f]+(?: [0-7]+)?|(?:new|deleted) file mode [0-7]+|(?:old|new) mode [0-7]+|(?:dis)?similarity index \d+%|(?:rename|copy) (?:from|to) \S+)\r?$`)
+
+func isExtendedHeader(l string) bool {
+	for _, prefix := range extendedHeaders {
+		if strings.HasPrefix(l, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExtendedHeader updates the current file from an extended header line.
+func (p *parser) parseExtendedHeader(l string) {
+	switch {
+	case strings.HasPrefix(l, "deleted file "):
+		p.file.Mode = DELETED
+		p.modeSet = true
+		p.file.OldMode = strings.TrimSpace(strings.TrimPrefix(l, "deleted file mode "))
+	case strings.HasPrefix(l, "new file "):
+		p.file.Mode = NEW
+		p.modeSet = true
+		p.file.NewMode = strings.TrimSpace(strings.TrimPrefix(l, "new file mode "))
+	case strings.HasPrefix(l, "rename "):
+		p.file.Mode = RENAMED
+		p.modeSet = true
+		p.parseHeaderName(l[len("rename "):])
+	case strings.HasPrefix(l, "copy "):
+		p.file.Mode = COPIED
+		p.modeSet = true
+		p.parseHeaderName(l[len("copy "):])
+	case strings.HasPrefix(l, "index "):
+		hashes, mode, _ := strings.Cut(l[len("index "):], " ")
+		p.file.OrigHash, p.file.NewHash, _ = strings.Cut(hashes, "..")
+		p.file.IndexMode = strings.TrimSpace(mode)
+	case strings.HasPrefix(l, "similarity index "):
+		similarity := strings.TrimSuffix(strings.TrimSpace(l[len("similarity index "):]), "%")
+		p.file.Similarity, _ = strconv.Atoi(similarity)
+	case strings.HasPrefix(l, "old mode "):
+		p.file.OldMode = strings.TrimSpace(l[len("old mode "):])
+	case strings.HasPrefix(l, "new mode "):
+		p.file.NewMode = strings.TrimSpace(l[len("new mode "):])
+	}
+}
+
+// parseHeaderName sets the current file's name from the rest of a "rename"
+// or "copy" line, such as "from old.txt". Unlike the "diff --git" line,
+// these name a single file, so names with spaces aren't ambiguous.
+func (p *parser) parseHeaderName(l string) {
+	side, name, ok := strings.Cut(strings.TrimSuffix(l, "\r"), " ")
+	if !ok || name == "" {
+		return
+	}
+	// git quotes names with unusual characters, escaping them like Go.
+	if strings.HasPrefix(name, `"`) {
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			name = unquoted
+		}
+	}
+	switch side {
+	case "from", "old":
+		p.file.OrigName = name
+	case "to", "new":
+		p.file.NewName = name
+	}
+}
+
+// mergeRepeatedFile replaces the file that's just been started with an
+// earlier one with the same names, if there is one, so that the following
+// hunks are added to it instead.
+func (p *parser) mergeRepeatedFile() {
+	earlier := p.diff.Files[:len(p.diff.Files)-1]
+	for _, f := range earlier {
+		if f.OrigName != p.file.OrigName || f.NewName != p.file.NewName {
+			continue
+		}
+
+		p.diff.Files = earlier
+		p.file = f
+		p.inHeader = false
+
+		// Carry on counting positions from the end of the earlier part.
+		if len(f.Hunks) > 0 {
+			p.firstHunkInFile = false
+			p.resumeFile = true
+		}
+		return
+	}
+}
+
+// included returns whether the hunks of the file should be parsed, according
+// to IncludePaths.
+func (p *parser) included(f *DiffFile) bool {
+	if len(p.IncludePaths) == 0 {
+		return true
+	}
+	for _, path := range p.IncludePaths {
+		if f.NewName == path || f.OrigName == path {
+			return true
+		}
+	}
+	return false
+}
+
+// lastPosition returns the position of the last line in the file's hunks.
+func lastPosition(f *DiffFile) int {
+	position := 0
+	for i, h := range f.Hunks {
+		if i > 0 {
+			position++
+		}
+		for _, l := range h.WholeRange.Lines {
+			position = l.Position
+			if l.NoNewline {
+				position++
 			}
 		}
 	}
+	return position
+}
+
+// hunkDone reports whether the current hunk has all the lines its header
+// says it should, after which any following lines aren't part of it.
+func (p *parser) hunkDone() bool {
+	return p.addedCount >= p.hunk.NewRange.Start+p.hunk.NewRange.Length &&
+		p.removedCount >= p.hunk.OrigRange.Start+p.hunk.OrigRange.Length
+}
+
+// headerName parses the filename from a "---" or "+++" line, with the
+// leading marker already removed. Anything after a tab, such as the
+// timestamp added by diff(1), the revision added by Subversion or the tab
+// git adds after names containing spaces, is returned separately as the
+// annotation. A trailing carriage return from a header with a different
+// line ending to the rest of the diff is ignored. The name is not ok if
+// it's "/dev/null", which is used in place of the name of an added or
+// deleted file.
+func headerName(s string, prefix byte) (name, annotation string, ok bool) {
+	s = strings.TrimSuffix(s, "\r")
+	name, annotation, _ = strings.Cut(s, "\t")
+	annotation = strings.TrimSpace(annotation)
+	if name == "/dev/null" {
+		return "", annotation, false
+	}
+	if hasNamePrefix(name, prefix) {
+		name = name[2:]
+	}
+	return name, annotation, true
+}
 
-	return &diff, nil
+// hasNamePrefix returns whether name starts with a prefix like the "a/" and
+// "b/" git adds to names, allowing for a backslash as the separator.
+func hasNamePrefix(name string, prefix byte) bool {
+	return len(name) > 2 && name[0] == prefix && (name[1] == '/' || name[1] == '\\')
 }
 
 func isSourceLine(line string) bool {
-	if line == `\ No newline at end of file` {
+	if strings.HasPrefix(line, `\ `) {
 		return false
 	}
-	if l := len(line); l == 0 || (l >= 3 && (line[:3] == "---" || line[:3] == "+++")) {
-		return false
+	// Lines starting with "---" or "+++" are only file headers outside of
+	// hunks, which is checked before this. In a hunk they're changes to
+	// lines starting with "--" or "++", such as in a diff of a diff.
+	return len(line) > 0
+}
+
+// addLine adds a copy of line to the hunk's ranges, numbering it from
+// origNumber and newNumber, which are advanced past it. If shareContext is
+// set, an unchanged line is added to all the ranges as a single copy.
+func (hunk *DiffHunk) addLine(line DiffLine, origNumber *int, newNumber *int, shareContext bool) {
+	newLine := line
+	origLine := line
+
+	// add lines to ranges
+	switch line.Mode {
+	case ADDED:
+		newLine.Number = *newNumber
+		newLine.NewNumber = *newNumber
+		hunk.NewRange.Lines = append(hunk.NewRange.Lines, &newLine)
+		hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &newLine)
+		*newNumber++
+
+	case REMOVED:
+		origLine.Number = *origNumber
+		origLine.OrigNumber = *origNumber
+		hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, &origLine)
+		hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &origLine)
+		*origNumber++
+
+	case UNCHANGED:
+		newLine.Number = *newNumber
+		newLine.OrigNumber = *origNumber
+		newLine.NewNumber = *newNumber
+		hunk.NewRange.Lines = append(hunk.NewRange.Lines, &newLine)
+		hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &newLine)
+		if shareContext {
+			hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, &newLine)
+		} else {
+			origLine.Number = *origNumber
+			origLine.OrigNumber = *origNumber
+			origLine.NewNumber = *newNumber
+			hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, &origLine)
+		}
+		*newNumber++
+		*origNumber++
 	}
-	return true
 }
 
-// Length returns the hunks line length
+// Length returns the number of lines the hunk takes up in the diff, which
+// is one for the "@@" header line, plus its LineCount, plus one for each
+// "\ No newline at end of file" marker. So an empty hunk, with only a
+// header, has a length of one.
 func (hunk *DiffHunk) Length() int {
-	return len(hunk.WholeRange.Lines) + 1
+	n := 1 + hunk.LineCount()
+	for _, l := range hunk.WholeRange.Lines {
+		if l.NoNewline {
+			n++
+		}
+	}
+	return n
+}
+
+// LineCount returns the number of lines in the body of the hunk, which is
+// the number of added, removed and unchanged lines.
+func (hunk *DiffHunk) LineCount() int {
+	// Unchanged lines are in both the OrigRange and NewRange, but
+	// WholeRange has each line of the body exactly once.
+	return len(hunk.WholeRange.Lines)
 }
@@ -4,8 +4,13 @@
 package diffparser
 
 import (
+	"bytes"
 	"cmp"
+	"compress/gzip"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -25,6 +30,24 @@ func setup(t *testing.T) *Diff {
 
 	return diff
 }
+
+func TestFileModeStatusLetter(t *testing.T) {
+	for _, mode := range []FileMode{DELETED, MODIFIED, NEW, RENAMED, COPIED} {
+		letter := mode.StatusLetter()
+		assert.Len(t, letter, 1, mode.String())
+
+		parsed, err := FileModeFromStatus(letter)
+		require.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+	assert.Equal(t, "A", NEW.StatusLetter())
+
+	_, err := FileModeFromStatus("X")
+	assert.Error(t, err)
+	_, err = FileModeFromStatus("")
+	assert.Error(t, err)
+}
+
 func TestFileModeAndNaming(t *testing.T) {
 	diff := setup(t)
 	for i, expected := range []struct {
@@ -92,49 +115,71 @@ func TestHunk(t *testing.T) {
 	diff := setup(t)
 	expectedOrigLines := []DiffLine{
 		{
-			Mode:     UNCHANGED,
-			Number:   1,
-			Content:  "some",
-			Position: 2,
+			Mode:         UNCHANGED,
+			Number:       1,
+			Content:      "some",
+			Position:     2,
+			OrigNumber:   1,
+			NewNumber:    2,
+			HunkPosition: 2,
 		}, {
-			Mode:     UNCHANGED,
-			Number:   2,
-			Content:  "lines",
-			Position: 3,
+			Mode:         UNCHANGED,
+			Number:       2,
+			Content:      "lines",
+			Position:     3,
+			OrigNumber:   2,
+			NewNumber:    3,
+			HunkPosition: 3,
 		}, {
-			Mode:     REMOVED,
-			Number:   3,
-			Content:  "in",
-			Position: 4,
+			Mode:         REMOVED,
+			Number:       3,
+			Content:      "in",
+			Position:     4,
+			OrigNumber:   3,
+			HunkPosition: 4,
 		}, {
-			Mode:     UNCHANGED,
-			Number:   4,
-			Content:  "file1",
-			Position: 5,
+			Mode:         UNCHANGED,
+			Number:       4,
+			Content:      "file1",
+			Position:     5,
+			OrigNumber:   4,
+			NewNumber:    4,
+			HunkPosition: 5,
 		},
 	}
 
 	expectedNewLines := []DiffLine{
 		{
-			Mode:     ADDED,
-			Number:   1,
-			Content:  "add a line",
-			Position: 1,
+			Mode:         ADDED,
+			Number:       1,
+			Content:      "add a line",
+			Position:     1,
+			NewNumber:    1,
+			HunkPosition: 1,
 		}, {
-			Mode:     UNCHANGED,
-			Number:   2,
-			Content:  "some",
-			Position: 2,
+			Mode:         UNCHANGED,
+			Number:       2,
+			Content:      "some",
+			Position:     2,
+			OrigNumber:   1,
+			NewNumber:    2,
+			HunkPosition: 2,
 		}, {
-			Mode:     UNCHANGED,
-			Number:   3,
-			Content:  "lines",
-			Position: 3,
+			Mode:         UNCHANGED,
+			Number:       3,
+			Content:      "lines",
+			Position:     3,
+			OrigNumber:   2,
+			NewNumber:    3,
+			HunkPosition: 3,
 		}, {
-			Mode:     UNCHANGED,
-			Number:   4,
-			Content:  "file1",
-			Position: 5,
+			Mode:         UNCHANGED,
+			Number:       4,
+			Content:      "file1",
+			Position:     5,
+			OrigNumber:   4,
+			NewNumber:    4,
+			HunkPosition: 5,
 		},
 	}
 
@@ -154,3 +199,1919 @@ func TestHunk(t *testing.T) {
 		require.Equal(t, line, *newRange.Lines[i])
 	}
 }
+
+func TestHunkWithoutLength(t *testing.T) {
+	diff, err := Parse(`--- a/file
++++ b/file
+@@ -10 +10 @@
+-old
++new
+--- a/other
++++ b/other
+@@ -1 +1 @@
+-a
++b
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	hunk := diff.Files[0].Hunks[0]
+	assert.Equal(t, 1, hunk.OrigRange.Length)
+	assert.Equal(t, 1, hunk.NewRange.Length)
+	assert.Len(t, hunk.WholeRange.Lines, 2)
+	assert.Equal(t, "other", diff.Files[1].NewName)
+}
+
+func TestHunkExtraLines(t *testing.T) {
+	// The hunk ends after the lines its header counts, so "+c" is ignored.
+	diff, err := Parse(`--- a/file
++++ b/file
+@@ -1 +1 @@
+-a
++b
++c
+--- a/other
++++ b/other
+@@ -1 +1 @@
+-d
++e
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	hunk := diff.Files[0].Hunks[0]
+	assert.Equal(t, "@@ -1 +1 @@\n-a\n+b\n", hunk.String())
+	require.Len(t, diff.Files[1].Hunks, 1)
+	assert.Len(t, diff.Files[1].Hunks[0].WholeRange.Lines, 2)
+}
+
+func TestParseReader(t *testing.T) {
+	byt, err := os.ReadFile("example.diff")
+	require.NoError(t, err)
+
+	expected, err := Parse(string(byt))
+	require.NoError(t, err)
+	diff, err := ParseReader(bytes.NewReader(byt))
+	require.NoError(t, err)
+	require.Equal(t, expected, diff)
+}
+
+func TestParseGzip(t *testing.T) {
+	byt, err := os.ReadFile("example.diff")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err = zw.Write(byt)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	expected, err := Parse(string(byt))
+	require.NoError(t, err)
+	diff, err := ParseGzip(&buf)
+	require.NoError(t, err)
+	require.Equal(t, expected, diff)
+
+	_, err = ParseGzip(bytes.NewReader(byt))
+	require.Error(t, err)
+}
+
+func TestParseReaderLongLine(t *testing.T) {
+	long := strings.Repeat("x", 4*1024*1024)
+	input := `diff --git a/min.js b/min.js
+index 504d2a1..50ccec3 100644
+--- a/min.js
++++ b/min.js
+@@ -1 +1 @@
+-` + long + `
++` + long + `;
+`
+
+	diff, err := ParseReader(strings.NewReader(input))
+	require.NoError(t, err)
+	lines := diff.Files[0].Hunks[0].WholeRange.Lines
+	require.Len(t, lines, 2)
+	require.Equal(t, long, lines[0].Content)
+	require.Equal(t, long+";", lines[1].Content)
+	require.Equal(t, input, diff.Raw)
+
+	parser := Parser{MaxLineBytes: 1024 * 1024}
+	_, err = parser.ParseReader(strings.NewReader(input))
+	require.EqualError(t, err, "line 6 is longer than the maximum of 1048576 bytes")
+}
+
+func TestHeaderNames(t *testing.T) {
+	// The "diff --git" line can't be split unambiguously when names contain
+	// spaces, so the "---" and "+++" lines should be used instead.
+	diff, err := Parse("diff --git a/foo b/bar b/foo b/bar\n" +
+		"index 504d2a1..50ccec3 100644\n" +
+		"--- a/foo b/bar\t\n" +
+		"+++ b/foo b/bar\t\n" +
+		"@@ -1 +1 @@\n" +
+		"-old\n" +
+		"+new\n")
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, "foo b/bar", diff.Files[0].OrigName)
+	assert.Equal(t, "foo b/bar", diff.Files[0].NewName)
+
+	// Names in "/dev/null" lines aren't real names.
+	diff, err = Parse("diff --git a/file b/file\n" +
+		"new file mode 100644\n" +
+		"index 0000000..57271b1\n" +
+		"--- /dev/null\n" +
+		"+++ b/file\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+added\n")
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, "file", diff.Files[0].OrigName)
+	assert.Equal(t, "file", diff.Files[0].NewName)
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	diff, err := Parse(`--- one.txt	2015-01-01 00:00:00.000000000 +0000
++++ two.txt	2015-01-02 00:00:00.000000000 +0000
+@@ -1,2 +1,2 @@
+ same
+-removed
++added
+--- three.txt	2015-01-01 00:00:00.000000000 +0000
++++ four.txt	2015-01-02 00:00:00.000000000 +0000
+@@ -1 +1 @@
+-before
++after
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	assert.Equal(t, "one.txt", diff.Files[0].OrigName)
+	assert.Equal(t, "two.txt", diff.Files[0].NewName)
+	require.Len(t, diff.Files[0].Hunks, 1)
+	assert.Len(t, diff.Files[0].Hunks[0].WholeRange.Lines, 3)
+
+	assert.Equal(t, "three.txt", diff.Files[1].OrigName)
+	assert.Equal(t, "four.txt", diff.Files[1].NewName)
+	require.Len(t, diff.Files[1].Hunks, 1)
+	assert.Len(t, diff.Files[1].Hunks[0].WholeRange.Lines, 2)
+}
+
+func TestHeaderNamesMixedLineEndings(t *testing.T) {
+	diff, err := Parse("diff --git a/file b/file\r\n" +
+		"index 504d2a1..50ccec3 100644\r\n" +
+		"--- a/file\r\n" +
+		"+++ b/file\r\n" +
+		"@@ -1 +1 @@\n" +
+		"-old\n" +
+		"+new\n" +
+		"diff --git a/added b/added\r\n" +
+		"new file mode 100644\r\n" +
+		"index 0000000..57271b1\r\n" +
+		"--- /dev/null\r\n" +
+		"+++ b/added\r\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+new\n")
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	assert.Equal(t, "file", diff.Files[0].OrigName)
+	assert.Equal(t, "file", diff.Files[0].NewName)
+	assert.Equal(t, "new", diff.Files[0].Hunks[0].NewRange.Lines[0].Content)
+
+	assert.Equal(t, NEW, diff.Files[1].Mode)
+	assert.Equal(t, "added", diff.Files[1].OrigName)
+	assert.Equal(t, "added", diff.Files[1].NewName)
+}
+
+func TestChangedRanges(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+index 504d2a1..50ccec3 100644
+--- a/file
++++ b/file
+@@ -1,3 +1,6 @@
++one
++two
+ three
++four
+ five
+-six
++seven
+@@ -10,1 +13,2 @@
+ thirteen
++fourteen
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][][2]int{
+		"file": {{1, 2}, {4, 4}, {6, 6}, {14, 14}},
+	}, diff.ChangedRanges())
+
+	diff = setup(t)
+	assert.Equal(t, map[string][][2]int{
+		"file1":   {{1, 1}},
+		"file4":   {{1, 1}},
+		"newname": {{1, 4}},
+	}, diff.ChangedRanges())
+}
+
+func TestChangedWithContextAnchors(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+index 504d2a1..50ccec3 100644
+--- a/file
++++ b/file
+@@ -1,3 +1,6 @@
++one
++two
+ three
++four
+ five
+-six
++seven
+@@ -10,1 +13,2 @@
+ thirteen
++fourteen
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]ChangeBlock{
+		"file": {
+			{Start: 1, End: 2, Anchor: 0},
+			{Start: 4, End: 4, Anchor: 3},
+			{Start: 6, End: 6, Anchor: 5},
+			{Start: 14, End: 14, Anchor: 13},
+		},
+	}, diff.ChangedWithContextAnchors())
+
+	diff = setup(t)
+	blocks := diff.ChangedWithContextAnchors()
+	assert.Equal(t, []ChangeBlock{{Start: 1, End: 1, Anchor: 0}}, blocks["file1"])
+	assert.NotContains(t, blocks, "file2")
+}
+
+func TestMercurialDiff(t *testing.T) {
+	diff, err := Parse(`diff -r 8c1d3a5b7f2e -r 4b2a9c0d1e3f hello.c
+--- a/hello.c	Thu Jan 01 00:00:00 1970 +0000
++++ b/hello.c	Thu Jan 01 00:00:01 1970 +0000
+@@ -1,3 +1,3 @@
+ #include <stdio.h>
+-int main() { printf("hello\n"); }
++int main() { printf("hello, world\n"); }
+ /* end */
+diff -r 8c1d3a5b7f2e -r 4b2a9c0d1e3f hello world.txt
+--- /dev/null	Thu Jan 01 00:00:00 1970 +0000
++++ b/hello world.txt	Thu Jan 01 00:00:01 1970 +0000
+@@ -0,0 +1,1 @@
++hello world
+diff -r 8c1d3a5b7f2e old.txt
+--- a/old.txt	Thu Jan 01 00:00:00 1970 +0000
++++ /dev/null	Thu Jan 01 00:00:00 1970 +0000
+@@ -1,1 +0,0 @@
+-old
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	for i, expected := range []struct {
+		mode     FileMode
+		origName string
+		newName  string
+	}{
+		{mode: MODIFIED, origName: "hello.c", newName: "hello.c"},
+		{mode: NEW, origName: "hello world.txt", newName: "hello world.txt"},
+		{mode: DELETED, origName: "old.txt", newName: "old.txt"},
+	} {
+		file := diff.Files[i]
+		assert.Equal(t, expected.mode, file.Mode)
+		assert.Equal(t, expected.origName, file.OrigName)
+		assert.Equal(t, expected.newName, file.NewName)
+		assert.Len(t, file.Hunks, 1)
+	}
+}
+
+func TestNewFileHunk(t *testing.T) {
+	diff, err := Parse(`diff --git a/new b/new
+new file mode 100644
+index 0000000..9a5d2a1
+--- /dev/null
++++ b/new
+@@ -0,0 +1,5 @@
++one
++two
++three
++four
++five
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	hunk := diff.Files[0].Hunks[0]
+
+	assert.Equal(t, 0, hunk.OrigRange.Start)
+	assert.Equal(t, 0, hunk.OrigRange.Length)
+	assert.Empty(t, hunk.OrigRange.Lines)
+	assert.Equal(t, 1, hunk.NewRange.Start)
+	assert.Equal(t, 5, hunk.NewRange.Length)
+	for i, l := range hunk.NewRange.Lines {
+		assert.Equal(t, ADDED, l.Mode)
+		assert.Equal(t, i+1, l.Number)
+	}
+
+	assert.Equal(t, map[string][]int{"new": {1, 2, 3, 4, 5}}, diff.Changed())
+}
+
+func TestStatPreamble(t *testing.T) {
+	diff, err := Parse(` file1             | 2 +-
+ rename from => to |  0
+ new file          | 1 +
+ 3 files changed, 2 insertions(+), 1 deletion(-)
+ new file mode 100644
+ rename from => to (100%)
+
+diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-some
++any
+ lines
+diff --git a/from b/to
+similarity index 100%
+rename from from
+rename to to
+diff --git a/new b/new
+new file mode 100644
+index 0000000..57271b1
+--- /dev/null
++++ b/new
+@@ -0,0 +1 @@
++added
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+	assert.Equal(t, MODIFIED, diff.Files[0].Mode)
+	assert.Equal(t, "file1", diff.Files[0].NewName)
+	assert.Equal(t, RENAMED, diff.Files[1].Mode)
+	assert.Equal(t, NEW, diff.Files[2].Mode)
+	assert.Equal(t, map[string][]int{"file1": {1}, "new": {1}}, diff.Changed())
+}
+
+func TestWideStatPreamble(t *testing.T) {
+	// With --stat=200, the bars are long runs of "+" and "-".
+	diff, err := Parse(` src/generated/tables.go | 150 ++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++------------------------------
+ README.md               |  20 ++++++++++++++++++++
+ 2 files changed, 140 insertions(+), 30 deletions(-)
+
+diff --git a/README.md b/README.md
+index 504d2a1..50ccec3 100644
+--- a/README.md
++++ b/README.md
+@@ -1,2 +1,2 @@
+-# Tables
++# Generated tables
+ See src/generated.
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, "README.md", diff.Files[0].NewName)
+	assert.Equal(t, map[string][]int{"README.md": {1}}, diff.Changed())
+	assert.Equal(t, 1, diff.Files[0].Hunks[0].WholeRange.Lines[0].Position)
+}
+
+func TestGitShow(t *testing.T) {
+	diff, err := Parse(`commit 482f0c55f27f26a34fc3c30b87a55144f0406f00 (HEAD -> main)
+Author: Jane Doe <jane@example.com>
+Date:   Fri Oct 16 09:39:22 2026 +0000
+
+    Fix the thing
+    
+    The diff for this was:
+    
+    diff --git a/file1 b/file1
+    --- a/file1
+    +++ b/file1
+    @@ -1 +1 @@
+    -old
+
+diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-some
++any
+ lines
+`)
+	require.NoError(t, err)
+	assert.Equal(t, "482f0c55f27f26a34fc3c30b87a55144f0406f00", diff.Commit)
+	assert.Equal(t, "Jane Doe <jane@example.com>", diff.Author)
+	assert.Equal(t, `Fix the thing
+
+The diff for this was:
+
+diff --git a/file1 b/file1
+--- a/file1
++++ b/file1
+@@ -1 +1 @@
+-old`, diff.Message)
+
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, "file1", diff.Files[0].NewName)
+	assert.Equal(t, map[string][]int{"file1": {1}}, diff.Changed())
+}
+
+func TestSingleContextLine(t *testing.T) {
+	byt, err := os.ReadFile("example.diff")
+	require.NoError(t, err)
+
+	parser := Parser{SingleContextLine: true}
+	diff, err := parser.Parse(string(byt))
+	require.NoError(t, err)
+
+	hunk := diff.Files[0].Hunks[0]
+	require.Len(t, hunk.OrigRange.Lines, 4)
+	require.Len(t, hunk.NewRange.Lines, 4)
+
+	// "some" is unchanged, and is shared between all the ranges.
+	assert.Same(t, hunk.NewRange.Lines[1], hunk.OrigRange.Lines[0])
+	assert.Same(t, hunk.WholeRange.Lines[1], hunk.OrigRange.Lines[0])
+	assert.Equal(t, DiffLine{
+		Mode:       UNCHANGED,
+		Number:     2,
+		Content:    "some",
+		Position:   2,
+		OrigNumber: 1,
+		NewNumber:  2,
+
+		HunkPosition: 2,
+	}, *hunk.OrigRange.Lines[0])
+
+	// "in" is removed, so only appears in the original.
+	assert.Same(t, hunk.WholeRange.Lines[3], hunk.OrigRange.Lines[2])
+
+	// Without the option, there are separate copies.
+	diff = setup(t)
+	hunk = diff.Files[0].Hunks[0]
+	assert.NotSame(t, hunk.NewRange.Lines[1], hunk.OrigRange.Lines[0])
+	assert.Equal(t, hunk.NewRange.Lines[1].Content, hunk.OrigRange.Lines[0].Content)
+}
+
+func TestRangeDiff(t *testing.T) {
+	_, err := Parse(`1:  a1b2c3d = 1:  e4f5a6b Add the first thing
+2:  1234567 ! 2:  89abcde Add the second thing
+    @@ file.c
+     int main() {
+    -	return 0;
+    +	return 1;
+     }
+-:  ------- > 3:  fedcba9 Add the third thing
+`)
+	require.ErrorIs(t, err, ErrRangeDiffUnsupported)
+
+	_, err = Parse(`-:  ------- > 1:  fedcba9 Add a thing
+`)
+	require.ErrorIs(t, err, ErrRangeDiffUnsupported)
+}
+
+func TestLineSide(t *testing.T) {
+	diff := setup(t)
+	lines := diff.Files[0].Hunks[0].WholeRange.Lines
+
+	for i, expected := range []struct {
+		side string
+		line int
+	}{
+		{side: "RIGHT", line: 1}, // +add a line
+		{side: "RIGHT", line: 2}, //  some
+		{side: "RIGHT", line: 3}, //  lines
+		{side: "LEFT", line: 3},  // -in
+		{side: "RIGHT", line: 4}, //  file1
+	} {
+		l := lines[i]
+		assert.Equal(t, expected.side, l.Side())
+		if l.Side() == "LEFT" {
+			assert.Equal(t, expected.line, l.OrigNumber)
+		} else {
+			assert.Equal(t, expected.line, l.NewNumber)
+		}
+	}
+}
+
+func TestLineTrimmedContent(t *testing.T) {
+	for _, content := range []string{
+		"if x == 1 {",
+		"\tif x == 1 {",
+		"    if  x ==\t1 {  ",
+		"if\t\tx == 1 {\r",
+	} {
+		l := DiffLine{Content: content}
+		assert.Equal(t, "if x == 1 {", l.TrimmedContent(), content)
+	}
+	assert.Equal(t, "", (&DiffLine{Content: " \t "}).TrimmedContent())
+}
+
+func TestNoIndex(t *testing.T) {
+	// Generated with "git diff --no-index /tmp/x /tmp/y".
+	diff, err := Parse(`diff --git a/tmp/x b/tmp/y
+index 422c2b7..0f7bc76 100644
+--- a/tmp/x
++++ b/tmp/y
+@@ -1,2 +1,2 @@
+ a
+-b
++c
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, "tmp/x", diff.Files[0].OrigName)
+	assert.Equal(t, "tmp/y", diff.Files[0].NewName)
+	assert.Equal(t, map[string][]int{"tmp/y": {2}}, diff.Changed())
+
+	// With --no-prefix, and without "---" and "+++" lines to fall back on.
+	diff, err = Parse(`diff --git tmp/x.bin tmp/y.bin
+index 422c2b7..0f7bc76 100644
+Binary files tmp/x.bin and tmp/y.bin differ
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, "tmp/x.bin", diff.Files[0].OrigName)
+	assert.Equal(t, "tmp/y.bin", diff.Files[0].NewName)
+}
+
+func TestForEachChangedLine(t *testing.T) {
+	diff := setup(t)
+
+	added := map[string]int{}
+	diff.ForEachChangedLine(func(file *DiffFile, line *DiffLine) {
+		assert.Equal(t, ADDED, line.Mode)
+		added[file.NewName]++
+	})
+	assert.Equal(t, map[string]int{"file1": 1, "file4": 1, "newname": 4}, added)
+
+	changed := map[string]int{}
+	diff.ForEachChangedLineIncludingRemoved(func(file *DiffFile, line *DiffLine) {
+		assert.NotEqual(t, UNCHANGED, line.Mode)
+		changed[file.NewName]++
+	})
+	assert.Equal(t, map[string]int{"file1": 2, "file2": 4, "file3": 4, "file4": 1, "newname": 4, "symlink": 1}, changed)
+}
+
+func TestTrailer(t *testing.T) {
+	diff, err := Parse(`From 482f0c55f27f26a34fc3c30b87a55144f0406f00 Mon Sep 17 00:00:00 2001
+From: Jane Doe <jane@example.com>
+Date: Fri, 16 Oct 2026 09:39:22 +0000
+Subject: [PATCH] Fix the thing
+
+---
+ file1 | 2 +-
+ 1 file changed, 1 insertion(+), 1 deletion(-)
+
+diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+- some
++ any
+ lines
+-- 
+-----BEGIN PGP SIGNATURE-----
+
+iQEzBAEBCAAdFiEE
+-----END PGP SIGNATURE-----
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	require.Len(t, diff.Files[0].Hunks, 1)
+	assert.Len(t, diff.Files[0].Hunks[0].WholeRange.Lines, 3)
+	assert.Equal(t, "-----BEGIN PGP SIGNATURE-----\n\niQEzBAEBCAAdFiEE\n-----END PGP SIGNATURE-----\n", diff.Trailer)
+
+	// A removed line of "- " inside a hunk isn't a separator.
+	diff, err = Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,1 @@
+- 
+ lines
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files[0].Hunks[0].OrigRange.Lines, 2)
+	assert.Equal(t, " ", diff.Files[0].Hunks[0].OrigRange.Lines[0].Content)
+	assert.Empty(t, diff.Trailer)
+}
+
+func TestPatchSeries(t *testing.T) {
+	diff, err := Parse(`From 482f0c55f27f26a34fc3c30b87a55144f0406f00 Mon Sep 17 00:00:00 2001
+From: Jane Doe <jane@example.com>
+Date: Fri, 16 Oct 2026 09:39:22 +0000
+Subject: [PATCH 2/5] Fix the thing
+
+---
+ file1 | 2 +-
+ 1 file changed, 1 insertion(+), 1 deletion(-)
+
+diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-some
++any
+ lines
+-- 
+2.40.0
+`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, diff.PatchNumber)
+	assert.Equal(t, 5, diff.PatchTotal)
+	require.Len(t, diff.Files, 1)
+
+	for subject, expected := range map[string][2]int{
+		"Subject: [PATCH] Fix":            {0, 0},
+		"Subject: [PATCH v2 03/12] Fix":   {3, 12},
+		"Subject: [RFC PATCH 1/1] Fix":    {1, 1},
+		"Subject: Fix [PATCH 1/2] things": {0, 0},
+	} {
+		diff, err := Parse(subject + "\n")
+		require.NoError(t, err)
+		assert.Equal(t, expected, [2]int{diff.PatchNumber, diff.PatchTotal}, subject)
+	}
+}
+
+func TestWindowsPaths(t *testing.T) {
+	diff, err := Parse(`diff --git a/C:/Users/foo/file.txt b/C:/Users/foo/file.txt
+index 504d2a1..50ccec3 100644
+--- a/C:/Users/foo/file.txt
++++ b/C:/Users/foo/file.txt
+@@ -1 +1 @@
+-old
++new
+diff --git a\C:\Users\foo\other.txt b\C:\Users\foo\other.txt
+index 504d2a1..50ccec3 100644
+Binary files a\C:\Users\foo\other.txt and b\C:\Users\foo\other.txt differ
+diff --git a\C:\Users\foo\new.txt b\C:\Users\foo\new.txt
+new file mode 100644
+index 0000000..50ccec3
+--- /dev/null
++++ b\C:\Users\foo\new.txt
+@@ -0,0 +1 @@
++new
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	assert.Equal(t, `C:/Users/foo/file.txt`, diff.Files[0].OrigName)
+	assert.Equal(t, `C:/Users/foo/file.txt`, diff.Files[0].NewName)
+	assert.Equal(t, `C:\Users\foo\other.txt`, diff.Files[1].OrigName)
+	assert.Equal(t, `C:\Users\foo\other.txt`, diff.Files[1].NewName)
+	assert.Equal(t, `C:\Users\foo\new.txt`, diff.Files[2].OrigName)
+	assert.Equal(t, `C:\Users\foo\new.txt`, diff.Files[2].NewName)
+}
+
+func TestChangedIncludingDeleted(t *testing.T) {
+	diff := setup(t)
+
+	assert.Equal(t, map[string][]int{
+		"file1":       {1},
+		"file2":       {1, 2, 3, 4},
+		"file3":       {1, 2, 3, 4},
+		"file4":       {1},
+		"newname":     {1, 2, 3, 4},
+		"symlink":     {1},
+		"deleteEmpty": {},
+	}, diff.ChangedIncludingDeleted())
+
+	// Changed is unaffected.
+	assert.NotContains(t, diff.Changed(), "file2")
+}
+
+func TestModeChange(t *testing.T) {
+	diff, err := Parse(` script.sh | 0
+ tool      | 2 +-
+ 2 files changed, 1 insertion(+), 1 deletion(-)
+ mode change 100644 => 100755 script.sh
+ mode change 100755 => 100644 tool
+
+diff --git a/script.sh b/script.sh
+old mode 100644
+new mode 100755
+diff --git a/tool b/tool
+index 504d2a1..50ccec3
+--- a/tool
++++ b/tool
+@@ -1 +1 @@
+-old
++new
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	assert.Equal(t, "script.sh", diff.Files[0].NewName)
+	assert.Equal(t, "100644", diff.Files[0].OldMode)
+	assert.Equal(t, "100755", diff.Files[0].NewMode)
+	assert.Equal(t, "tool", diff.Files[1].NewName)
+	assert.Equal(t, "100755", diff.Files[1].OldMode)
+	assert.Equal(t, "100644", diff.Files[1].NewMode)
+
+	// Without a patch, there's only the summary.
+	diff, err = Parse(" mode change 100644 => 100755 bin/run\n")
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, MODIFIED, diff.Files[0].Mode)
+	assert.Equal(t, "bin/run", diff.Files[0].NewName)
+	assert.Equal(t, "100644", diff.Files[0].OldMode)
+	assert.Equal(t, "100755", diff.Files[0].NewMode)
+}
+
+func TestHunkPosition(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+index 504d2a1..50ccec3 100644
+--- a/file
++++ b/file
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+@@ -10,2 +10,3 @@
+ ten
++ten and a half
+ eleven
+`)
+	require.NoError(t, err)
+	hunks := diff.Files[0].Hunks
+	require.Len(t, hunks, 2)
+
+	var positions, hunkPositions []int
+	for _, h := range hunks {
+		for _, l := range h.WholeRange.Lines {
+			positions = append(positions, l.Position)
+			hunkPositions = append(hunkPositions, l.HunkPosition)
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3, 5, 6, 7}, positions)
+	assert.Equal(t, []int{1, 2, 3, 1, 2, 3}, hunkPositions)
+}
+
+func TestFileChanged(t *testing.T) {
+	diff := setup(t)
+	changed := diff.Changed()
+
+	for _, f := range diff.Files {
+		assert.Equal(t, changed[f.NewName], f.Changed(), f.NewName)
+	}
+	assert.Equal(t, []int{1}, diff.Files[0].Changed())
+	assert.Empty(t, diff.Files[1].Changed())
+}
+
+func TestHunkGaps(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+index 504d2a1..50ccec3 100644
+--- a/file
++++ b/file
+@@ -1,3 +1,4 @@
+ one
++one and a half
+ two
+ three
+@@ -10,3 +11,2 @@
+ ten
+-eleven
+ twelve
+@@ -13,2 +13,3 @@
+ thirteen
++thirteen and a half
+ fourteen
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][2]int{{5, 10}}, diff.HunkGaps("file"))
+	assert.Nil(t, diff.HunkGaps("missing"))
+
+	diff = setup(t)
+	assert.Empty(t, diff.HunkGaps("file1"))
+}
+
+func TestFile(t *testing.T) {
+	diff := setup(t)
+
+	assert.Same(t, diff.Files[0], diff.File("file1"))
+	assert.Same(t, diff.Files[8], diff.File("old"))
+	assert.Same(t, diff.Files[8], diff.File("new"))
+	assert.Nil(t, diff.File("missing"))
+}
+
+func TestCounts(t *testing.T) {
+	diff, err := Parse(`diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,3 @@
+ package main
++
+ import "fmt"
+@@ -10,2 +11,2 @@ func main() {
+-	fmt.Println("hi")
++	fmt.Println("hello")
+ }
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1,1 +1,1 @@
+-# Hi
++# Hello
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, diff.Len())
+	assert.Equal(t, 2, diff.Files[0].HunkCount())
+	assert.Equal(t, 3, diff.Files[0].Hunks[0].LineCount())
+	assert.Equal(t, 3, diff.Files[0].Hunks[1].LineCount())
+	assert.Equal(t, 1, diff.Files[1].HunkCount())
+	assert.Equal(t, 2, diff.Files[1].Hunks[0].LineCount())
+
+	assert.Equal(t, 0, (&Diff{}).Len())
+
+	assert.Equal(t, 3, diff.HunkCount())
+	assert.Equal(t, 8, diff.LineCount())
+	assert.Equal(t, 0, (&Diff{}).HunkCount())
+	assert.Equal(t, 0, (&Diff{}).LineCount())
+
+	diff = setup(t)
+	assert.Equal(t, 6, diff.HunkCount())
+	assert.Equal(t, 19, diff.LineCount())
+}
+
+func TestStructEqual(t *testing.T) {
+	diff := setup(t)
+	assert.True(t, diff.StructEqual(setup(t)))
+
+	// Text before the diff changes Raw, but not the structure.
+	prefixed, err := Parse(" 9 files changed\n\n" + diff.Raw)
+	require.NoError(t, err)
+	assert.True(t, diff.StructEqual(prefixed))
+
+	other := setup(t)
+	other.Files[0].Hunks[0].WholeRange.Lines[0].Position = 100
+	assert.True(t, diff.StructEqual(other))
+	other.Files[0].Hunks[0].WholeRange.Lines[0].Content = "changed"
+	assert.False(t, diff.StructEqual(other))
+
+	other = setup(t)
+	other.Files = other.Files[1:]
+	assert.False(t, diff.StructEqual(other))
+
+	other = setup(t)
+	other.Files[2].NewName = "renamed"
+	assert.False(t, diff.StructEqual(other))
+}
+
+func TestWalkFiles(t *testing.T) {
+	diff := setup(t)
+
+	var names []string
+	require.NoError(t, diff.WalkFiles(func(f *DiffFile) error {
+		names = append(names, f.NewName)
+		return nil
+	}))
+	assert.Len(t, names, 9)
+
+	errStop := errors.New("stop")
+	names = nil
+	err := diff.WalkFiles(func(f *DiffFile) error {
+		names = append(names, f.NewName)
+		if f.Mode == DELETED {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, []string{"file1", "file2"}, names)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, setup(t).Validate())
+
+	diff, err := Parse(`diff --git a/file b/file
+--- a/file
++++ b/file
+@@ -10,2 +10,3 @@
+ ten
++ten and a half
+ eleven
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+`)
+	require.NoError(t, err)
+	assert.EqualError(t, diff.Validate(), "file: hunk 2 starts at line 1, which is before the end of the hunk before it at line 12")
+
+	// Hunks can't overlap either.
+	diff, err = Parse(`diff --git a/file b/file
+--- a/file
++++ b/file
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+@@ -3,2 +3,2 @@
+ three
+-four
++FOUR
+`)
+	require.NoError(t, err)
+	assert.Error(t, diff.Validate())
+}
+
+func TestContainsPath(t *testing.T) {
+	diff := setup(t)
+
+	assert.True(t, diff.ContainsPath("file1"))
+	assert.True(t, diff.ContainsPath("old"))
+	assert.True(t, diff.ContainsPath("new"))
+	assert.False(t, diff.ContainsPath("go.mod"))
+	assert.False(t, diff.ContainsPath(""))
+}
+
+func TestRemoveFile(t *testing.T) {
+	diff := setup(t)
+	raw := diff.Raw
+
+	require.True(t, diff.RemoveFile("file2"))
+	require.True(t, diff.RemoveFile("new"))
+	assert.False(t, diff.RemoveFile("file2"))
+	assert.False(t, diff.RemoveFile("missing"))
+
+	var names []string
+	for _, f := range diff.Files {
+		names = append(names, f.NewName)
+	}
+	assert.Equal(t, []string{"file1", "file3", "file4", "newname", "symlink", "newEmpty", "deleteEmpty"}, names)
+	assert.Nil(t, diff.File("file2"))
+	assert.Equal(t, raw, diff.Raw)
+}
+
+func TestAllowInterleaved(t *testing.T) {
+	input := `diff --git a/a b/a
+index 504d2a1..50ccec3 100644
+--- a/a
++++ b/a
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+diff --git a/b b/b
+index 504d2a1..50ccec3 100644
+--- a/b
++++ b/b
+@@ -1 +1 @@
+-bee
++BEE
+diff --git a/a b/a
+index 504d2a1..50ccec3 100644
+--- a/a
++++ b/a
+@@ -10,2 +10,2 @@
+ ten
+-eleven
++ELEVEN
+`
+
+	diff, err := Parse(input)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	parser := Parser{AllowInterleaved: true}
+	diff, err = parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	assert.Equal(t, "a", diff.Files[0].NewName)
+	require.Len(t, diff.Files[0].Hunks, 2)
+	assert.Equal(t, 10, diff.Files[0].Hunks[1].OrigRange.Start)
+	assert.Equal(t, map[string][]int{"a": {1, 11}, "b": {1}}, diff.Changed())
+	assert.NoError(t, diff.Files[0].CheckPositions())
+
+	assert.Equal(t, "b", diff.Files[1].NewName)
+	require.Len(t, diff.Files[1].Hunks, 1)
+}
+
+func TestReorderedHeaders(t *testing.T) {
+	diff, err := Parse(`index 3b18e51..8b13789 100644
+diff --git a/hello.txt b/hello.txt
+--- a/hello.txt
++++ b/hello.txt
+@@ -1,1 +1,1 @@
+-hello
++hello world
+new file mode 100644
+index 0000000..ce01362
+diff --git a/new.txt b/new.txt
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,1 @@
++new
+diff --git a/script.sh b/script.sh
+--- a/script.sh
++++ b/script.sh
+old mode 100644
+new mode 100755
+index 1f2e3d4..5a6b7c8
+@@ -1,1 +1,1 @@
+-echo hi
++echo hello
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	hello := diff.Files[0]
+	assert.Equal(t, MODIFIED, hello.Mode)
+	assert.Equal(t, "index 3b18e51..8b13789 100644\ndiff --git a/hello.txt b/hello.txt\n--- a/hello.txt\n+++ b/hello.txt", hello.DiffHeader)
+	require.Len(t, hello.Hunks, 1)
+	assert.Len(t, hello.Hunks[0].WholeRange.Lines, 2)
+
+	added := diff.Files[1]
+	assert.Equal(t, NEW, added.Mode)
+	assert.Equal(t, "new.txt", added.NewName)
+	assert.Equal(t, "new file mode 100644\nindex 0000000..ce01362\ndiff --git a/new.txt b/new.txt\n--- /dev/null\n+++ b/new.txt", added.DiffHeader)
+	assert.Equal(t, []int{1}, added.Changed())
+
+	script := diff.Files[2]
+	assert.Equal(t, MODIFIED, script.Mode)
+	assert.Equal(t, "100644", script.OldMode)
+	assert.Equal(t, "100755", script.NewMode)
+	assert.Equal(t, "diff --git a/script.sh b/script.sh\n--- a/script.sh\n+++ b/script.sh\nold mode 100644\nnew mode 100755\nindex 1f2e3d4..5a6b7c8", script.DiffHeader)
+	assert.Equal(t, 2, script.Hunks[0].NewRange.Lines[0].Position)
+}
+
+func TestTextConv(t *testing.T) {
+	diff, err := Parse(`diff --git a/logo.png b/logo.png
+index 3f4a2b1..9c8d7e6 100644
+--- a/logo.png
++++ b/logo.png
+@@ -1,3 +1,3 @@
+ ExifTool Version Number         : 12.40
+-Image Width                     : 64
++Image Width                     : 128
+ Image Height                    : 64
+diff --git a/icon.png b/icon.png
+index 1a2b3c4..5d6e7f8 100644
+Binary files a/icon.png and b/icon.png differ
+diff --git a/README.md b/README.md
+index 1111111..2222222 100644
+--- a/README.md
++++ b/README.md
+@@ -1,1 +1,1 @@
+-![logo](logo.png)
++![Logo](logo.png)
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	logo := diff.Files[0]
+	assert.True(t, logo.TextConv)
+	assert.False(t, logo.Binary)
+	assert.Equal(t, []int{2}, logo.Changed())
+
+	icon := diff.Files[1]
+	assert.False(t, icon.TextConv)
+	assert.True(t, icon.Binary)
+
+	assert.False(t, diff.Files[2].TextConv)
+}
+
+func TestBinaryPatchWithText(t *testing.T) {
+	diff, err := Parse(`diff --git a/main.go b/main.go
+index 0d1e2f3..4a5b6c7 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+ 
+-func main() {}
++func main() { run() }
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000000000000000000000000000000000000..2d4f8e1a9b3c5d7e6f8a0b1c2d3e4f5a6b7c8d9e
+GIT binary patch
+literal 68
+zcmeAS@N?(olHy~uVBq!ia0vp^j3CU&3?x-=hn)gaEa{HEjtmSN~?>!lvI6;R0X~wF
+z|Nl7{G!bXq@)Is5Ar*6yfAFkd;Nd&Ipe?|oc*>IM0SW9()78&qol;+0P{cssI20
+
+literal 0
+HcmV?d00001
+
+diff --git a/README.md b/README.md
+index 1111111..2222222 100644
+--- a/README.md
++++ b/README.md
+@@ -1,1 +1,2 @@
+ # Example
++![logo](logo.png)
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	main := diff.Files[0]
+	assert.False(t, main.Binary)
+	assert.Equal(t, []int{3}, main.Changed())
+
+	logo := diff.Files[1]
+	assert.True(t, logo.Binary)
+	assert.Equal(t, NEW, logo.Mode)
+	assert.Equal(t, "logo.png", logo.NewName)
+	assert.Empty(t, logo.Hunks)
+
+	readme := diff.Files[2]
+	assert.False(t, readme.Binary)
+	assert.Equal(t, MODIFIED, readme.Mode)
+	assert.Equal(t, []int{2}, readme.Changed())
+	assert.Equal(t, 2, readme.Hunks[0].NewRange.Lines[1].Position)
+}
+
+func TestHunkHeaderNoSpace(t *testing.T) {
+	diff, err := Parse(`diff --git a/hello.txt b/hello.txt
+--- a/hello.txt
++++ b/hello.txt
+@@-1,2 +1,2 @@ greeting
+ hello
+-world
++there
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	require.Len(t, diff.Files[0].Hunks, 1)
+
+	hunk := diff.Files[0].Hunks[0]
+	assert.Equal(t, "1,2", hunk.OrigRange.String())
+	assert.Equal(t, "1,2", hunk.NewRange.String())
+	assert.Equal(t, "greeting", hunk.Section())
+	assert.Equal(t, []int{2}, diff.Files[0].Changed())
+}
+
+func TestBazaarDiff(t *testing.T) {
+	diff, err := Parse(`=== added directory 'docs'
+=== added file 'docs/intro.txt'
+--- docs/intro.txt	1970-01-01 00:00:00 +0000
++++ docs/intro.txt	2020-06-01 12:00:00 +0000
+@@ -0,0 +1,1 @@
++Welcome!
+
+=== modified file 'hello.py'
+--- hello.py	2020-05-01 12:00:00 +0000
++++ hello.py	2020-06-01 12:00:00 +0000
+@@ -1,2 +1,2 @@
+ def main():
+-    print("hello")
++    print("hello, world")
+
+=== removed file 'old.txt'
+--- old.txt	2020-05-01 12:00:00 +0000
++++ old.txt	1970-01-01 00:00:00 +0000
+@@ -1,1 +0,0 @@
+-old
+
+=== renamed file 'before.txt' => 'after.txt'
+--- before.txt	2020-05-01 12:00:00 +0000
++++ after.txt	2020-06-01 12:00:00 +0000
+@@ -1,1 +1,1 @@
+-before
++after
+
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 4)
+
+	for i, expected := range []struct {
+		mode     FileMode
+		origName string
+		newName  string
+		changed  []int
+	}{
+		{mode: NEW, origName: "docs/intro.txt", newName: "docs/intro.txt", changed: []int{1}},
+		{mode: MODIFIED, origName: "hello.py", newName: "hello.py", changed: []int{2}},
+		{mode: DELETED, origName: "old.txt", newName: "old.txt"},
+		{mode: RENAMED, origName: "before.txt", newName: "after.txt", changed: []int{1}},
+	} {
+		file := diff.Files[i]
+		assert.Equal(t, expected.mode, file.Mode)
+		assert.Equal(t, expected.origName, file.OrigName)
+		assert.Equal(t, expected.newName, file.NewName)
+		assert.Equal(t, expected.changed, file.Changed())
+		assert.Len(t, file.Hunks, 1)
+	}
+}
+
+func TestIncludePaths(t *testing.T) {
+	byt, err := os.ReadFile("example.diff")
+	require.NoError(t, err)
+
+	parser := Parser{IncludePaths: []string{"file1", "old"}}
+	diff, err := parser.Parse(string(byt))
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 9)
+
+	expected := setup(t)
+	for i, file := range diff.Files {
+		assert.Equal(t, expected.Files[i].NewName, file.NewName)
+		assert.Equal(t, expected.Files[i].Mode, file.Mode)
+		switch file.NewName {
+		case "file1", "new":
+			assert.Equal(t, expected.Files[i].Hunks, file.Hunks)
+		default:
+			assert.Empty(t, file.Hunks, file.NewName)
+		}
+	}
+}
+
+func BenchmarkIncludePaths(b *testing.B) {
+	var sb strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&sb, "diff --git a/file%d b/file%d\n--- a/file%d\n+++ b/file%d\n@@ -1,20 +1,20 @@\n", i, i, i, i)
+		for j := 0; j < 20; j++ {
+			fmt.Fprintf(&sb, "-old line %d\n+new line %d\n", j, j)
+		}
+	}
+	diff := sb.String()
+
+	for _, bc := range []struct {
+		name   string
+		parser Parser
+	}{
+		{name: "all", parser: Parser{}},
+		{name: "one", parser: Parser{IncludePaths: []string{"file500"}}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := bc.parser.Parse(diff); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRenameNameWithSpace(t *testing.T) {
+	diff, err := Parse(`diff --git a/b.txt b/name with space.txt
+similarity index 100%
+rename from b.txt
+rename to name with space.txt
+diff --git a/tab b/"tab\tname"
+similarity index 100%
+copy from tab
+copy to "tab\tname"
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	renamed := diff.Files[0]
+	assert.Equal(t, RENAMED, renamed.Mode)
+	assert.Equal(t, "b.txt", renamed.OrigName)
+	assert.Equal(t, "name with space.txt", renamed.NewName)
+	assert.Contains(t, renamed.String(), "rename from b.txt\nrename to name with space.txt\n")
+
+	copied := diff.Files[1]
+	assert.Equal(t, COPIED, copied.Mode)
+	assert.Equal(t, "tab", copied.OrigName)
+	assert.Equal(t, "tab\tname", copied.NewName)
+
+	// The file from a "--raw" line is the same one.
+	diff, err = Parse(":100644 100644 e69de29 e69de29 R100\tb.txt\tname with space.txt\n" +
+		"\n" +
+		"diff --git a/b.txt b/name with space.txt\n" +
+		"similarity index 100%\n" +
+		"rename from b.txt\n" +
+		"rename to name with space.txt\n")
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, "100644", diff.Files[0].OldMode)
+}
+
+func TestInferRename(t *testing.T) {
+	diff, err := Parse(`diff --git src/old.c src/new.c
+--- src/old.c
++++ src/new.c
+@@ -1,1 +1,1 @@
+-int x;
++int y;
+--- /dev/null
++++ added.c
+@@ -0,0 +1,1 @@
++int z;
+diff --git a/orig.c b/copy.c
+similarity index 90%
+copy from orig.c
+copy to copy.c
+--- a/orig.c
++++ b/copy.c
+@@ -1,1 +1,1 @@
+-int a;
++int b;
+--- same.c
++++ same.c
+@@ -1,1 +1,1 @@
+-int c;
++int d;
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 4)
+
+	for i, expected := range []struct {
+		mode     FileMode
+		origName string
+		newName  string
+	}{
+		{mode: RENAMED, origName: "src/old.c", newName: "src/new.c"},
+		{mode: NEW, origName: "added.c", newName: "added.c"},
+		{mode: COPIED, origName: "orig.c", newName: "copy.c"},
+		{mode: MODIFIED, origName: "same.c", newName: "same.c"},
+	} {
+		file := diff.Files[i]
+		assert.Equal(t, expected.mode, file.Mode)
+		assert.Equal(t, expected.origName, file.OrigName)
+		assert.Equal(t, expected.newName, file.NewName)
+	}
+
+	// Plain diff(1) output compares files with different names without
+	// renaming them, like ParseContext.
+	diff, err = Parse(`--- file.orig	2020-01-01 12:00:00.000000000 +0000
++++ file	2020-01-02 12:00:00.000000000 +0000
+@@ -1 +1 @@
+-a
++b
+diff -ru old/x new/x
+--- old/x
++++ new/x
+@@ -1 +1 @@
+-c
++d
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+	assert.Equal(t, MODIFIED, diff.Files[0].Mode)
+	assert.Equal(t, "file.orig", diff.Files[0].OrigName)
+	assert.Equal(t, MODIFIED, diff.Files[1].Mode)
+}
+
+func TestIndexHashes(t *testing.T) {
+	diff := setup(t)
+
+	assert.Equal(t, "504d2a1", diff.Files[0].OrigHash)
+	assert.Equal(t, "50ccec3", diff.Files[0].NewHash)
+}
+
+func TestDiffOfDiff(t *testing.T) {
+	diff, err := Parse(`diff --git a/fix.patch b/fix.patch
+index 1234567..89abcde 100644
+--- a/fix.patch
++++ b/fix.patch
+@@ -1,6 +1,9 @@
+ diff --git a/main.go b/main.go
+---- a/main.go
+-+++ b/main.go
++--- a/main.go
+++++ b/main.go
+ @@ -1,1 +1,1 @@
+ -old
+ +new
++@@ -10,1 +10,1 @@
++-before
+++after
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+
+	file := diff.Files[0]
+	assert.Equal(t, "fix.patch", file.NewName)
+	require.Len(t, file.Hunks, 1)
+
+	hunk := file.Hunks[0]
+	assert.Len(t, hunk.OrigRange.Lines, 6)
+	assert.Len(t, hunk.NewRange.Lines, 9)
+	assert.Equal(t, "--- a/main.go", hunk.OrigRange.Lines[1].Content)
+	assert.Equal(t, REMOVED, hunk.OrigRange.Lines[1].Mode)
+	assert.Equal(t, "+++ b/main.go", hunk.NewRange.Lines[2].Content)
+	assert.Equal(t, ADDED, hunk.NewRange.Lines[2].Mode)
+
+	line := hunk.NewRange.LineAt(7)
+	require.NotNil(t, line)
+	assert.Equal(t, ADDED, line.Mode)
+	assert.Equal(t, "@@ -10,1 +10,1 @@", line.Content)
+	assert.Equal(t, []int{2, 3, 7, 8, 9}, file.Changed())
+}
+
+func TestSubversionDiff(t *testing.T) {
+	diff, err := Parse(`Index: trunk/src/main.c
+===================================================================
+--- trunk/src/main.c	(revision 1234)
++++ trunk/src/main.c	(working copy)
+@@ -1,3 +1,3 @@
+ #include <stdio.h>
+-int x = 1;
++int x = 2;
+ int y;
+Index: trunk/README
+===================================================================
+--- trunk/README	(revision 1234)
++++ trunk/README	(working copy)
+@@ -1,1 +1,2 @@
+ Hello
++World
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	main := diff.Files[0]
+	assert.Equal(t, MODIFIED, main.Mode)
+	assert.Equal(t, "trunk/src/main.c", main.OrigName)
+	assert.Equal(t, "trunk/src/main.c", main.NewName)
+	assert.Equal(t, "(revision 1234)", main.OrigAnnotation)
+	assert.Equal(t, "(working copy)", main.NewAnnotation)
+	assert.Equal(t, []int{2}, main.Changed())
+
+	readme := diff.Files[1]
+	assert.Equal(t, "trunk/README", readme.NewName)
+	assert.Equal(t, "(working copy)", readme.NewAnnotation)
+	assert.Equal(t, []int{2}, readme.Changed())
+}
+
+func TestNewAndDeletedFileModes(t *testing.T) {
+	raw := `diff --git a/run.sh b/run.sh
+new file mode 100755
+index 0000000..1a2b3c4
+--- /dev/null
++++ b/run.sh
+@@ -0,0 +1,2 @@
++#!/bin/sh
++echo hello
+diff --git a/link b/link
+deleted file mode 120000
+index 5d6e7f8..0000000
+--- a/link
++++ /dev/null
+@@ -1 +0,0 @@
+-target
+\ No newline at end of file
+`
+	diff, err := Parse(raw)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	run := diff.Files[0]
+	assert.Equal(t, NEW, run.Mode)
+	assert.Equal(t, "", run.OldMode)
+	assert.Equal(t, "100755", run.NewMode)
+
+	link := diff.Files[1]
+	assert.Equal(t, DELETED, link.Mode)
+	assert.Equal(t, "120000", link.OldMode)
+	assert.Equal(t, "", link.NewMode)
+
+	assert.Equal(t, raw, diff.String())
+}
+
+func TestPatchWithRaw(t *testing.T) {
+	diff, err := Parse(`:100644 100644 504d2a1 50ccec3 M	file1
+:100644 100644 fedcba9 fedcba9 R100	from	to
+:100644 100755 1111111 1111111 M	script.sh
+:000000 100644 0000000 57271b1 A	new
+
+diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-some
++any
+ lines
+diff --git a/from b/to
+similarity index 100%
+rename from from
+rename to to
+diff --git a/new b/new
+new file mode 100644
+index 0000000..57271b1
+--- /dev/null
++++ b/new
+@@ -0,0 +1,1 @@
++added
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 4)
+
+	file1 := diff.Files[0]
+	assert.Equal(t, MODIFIED, file1.Mode)
+	assert.Equal(t, "100644", file1.OldMode)
+	assert.Equal(t, "100644", file1.NewMode)
+	assert.Equal(t, "504d2a1", file1.OrigHash)
+	assert.Equal(t, []int{1}, file1.Changed())
+
+	renamed := diff.Files[1]
+	assert.Equal(t, RENAMED, renamed.Mode)
+	assert.Equal(t, "fedcba9", renamed.OrigHash)
+	assert.Equal(t, "fedcba9", renamed.NewHash)
+	assert.Equal(t, 100, renamed.Similarity)
+
+	added := diff.Files[2]
+	assert.Equal(t, NEW, added.Mode)
+	assert.Equal(t, "000000", added.OldMode)
+	assert.Equal(t, "100644", added.NewMode)
+	assert.Equal(t, []int{1}, added.Changed())
+
+	// Files that are only in the raw lines are added too.
+	script := diff.Files[3]
+	assert.Equal(t, "script.sh", script.NewName)
+	assert.Equal(t, "100644", script.OldMode)
+	assert.Equal(t, "100755", script.NewMode)
+	assert.Empty(t, script.Hunks)
+}
+
+func TestBinaryPatchBody(t *testing.T) {
+	// The binary patch has a forward and a reverse delta, with CRLF line
+	// endings.
+	input := strings.ReplaceAll(`diff --git a/data.bin b/data.bin
+index 3f4a2b1..9c8d7e6 100644
+GIT binary patch
+delta 52
+zcmV-40L%ZX+5sAw0RRAh0002D0002z0002M00000Hw6Wk0002q0002M000000001h
+z0000KS9M-rP;@P08
+delta 48
+zcmV-00MGxtK_x@UYG00IF60RRA7K>z>%Apif5Apid@0RRA#03*0UNdN!<
+
+diff --git a/main.go b/main.go
+index 0d1e2f3..4a5b6c7 100644
+--- a/main.go
++++ b/main.go
+@@ -1,1 +1,1 @@
+-package old
++package main
+`, "\n", "\r\n")
+
+	for _, parse := range []func(string) (*Diff, error){
+		Parse,
+		func(s string) (*Diff, error) { return ParseReader(strings.NewReader(s)) },
+	} {
+		diff, err := parse(input)
+		require.NoError(t, err)
+		require.Len(t, diff.Files, 2)
+
+		data := diff.Files[0]
+		assert.True(t, data.Binary)
+		assert.Empty(t, data.Hunks)
+
+		main := diff.Files[1]
+		assert.False(t, main.Binary)
+		assert.Equal(t, "main.go", main.NewName)
+		assert.Equal(t, []int{1}, main.Changed())
+	}
+}
+
+func TestIgnorePrefixes(t *testing.T) {
+	input := `# running git diff
+diff --git a/main.go b/main.go
+> fetching objects
+index 0d1e2f3..4a5b6c7 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-# not a log line
++# still not a log line
+ > or this
+# between hunks
+@@ -10,1 +10,1 @@
+-old
++new
+# done
+`
+
+	parser := Parser{IgnorePrefixes: []string{"# ", "> "}}
+	diff, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+
+	file := diff.Files[0]
+	assert.Equal(t, "0d1e2f3", file.OrigHash)
+	require.Len(t, file.Hunks, 2)
+	assert.Equal(t, "# not a log line", file.Hunks[0].OrigRange.Lines[1].Content)
+	assert.Equal(t, "# still not a log line", file.Hunks[0].NewRange.Lines[1].Content)
+	assert.Equal(t, "> or this", file.Hunks[0].NewRange.Lines[2].Content)
+	assert.Equal(t, []int{2, 10}, file.Changed())
+	assert.NoError(t, file.CheckPositions())
+
+	// Without IgnorePrefixes, the log line between the hunks is counted in
+	// the positions.
+	diff, err = Parse(input)
+	require.NoError(t, err)
+	assert.Error(t, diff.Files[0].CheckPositions())
+}
+
+func TestLenientTabContext(t *testing.T) {
+	input := "diff --git a/main.go b/main.go\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -1,4 +1,4 @@\n" +
+		"\tfunc main() {\n" +
+		"-\tprintln(\"hi\")\n" +
+		"+\tprintln(\"hello\")\n" +
+		"\t\treturn\n" +
+		" }\n"
+
+	_, err := Parse(input)
+	require.Error(t, err)
+
+	parser := Parser{Lenient: true}
+	diff, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	hunk := diff.Files[0].Hunks[0]
+	require.Len(t, hunk.WholeRange.Lines, 5)
+	assert.Equal(t, UNCHANGED, hunk.WholeRange.Lines[0].Mode)
+	assert.Equal(t, "\tfunc main() {", hunk.WholeRange.Lines[0].Content)
+	assert.Equal(t, UNCHANGED, hunk.WholeRange.Lines[3].Mode)
+	assert.Equal(t, "\t\treturn", hunk.WholeRange.Lines[3].Content)
+	assert.Equal(t, 3, hunk.NewRange.Lines[2].Number)
+	assert.Equal(t, []int{2}, diff.Files[0].Changed())
+}
+
+func TestZeroStartRange(t *testing.T) {
+	diff, err := Parse(`diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +0,3 @@
++one
++two
++three
+`)
+	require.NoError(t, err)
+
+	hunk := diff.Files[0].Hunks[0]
+	assert.Equal(t, "0,0", hunk.OrigRange.String())
+	assert.Equal(t, "1,3", hunk.NewRange.String())
+	assert.Equal(t, []int{1, 2, 3}, diff.Files[0].Changed())
+	assert.Equal(t, 1, hunk.NewRange.Lines[0].NewNumber)
+}
+
+func TestNoNewlineOnUnchangedLine(t *testing.T) {
+	raw := `diff --git a/file b/file
+index 1111111..2222222 100644
+--- a/file
++++ b/file
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+\ No newline at end of file
+`
+	for _, single := range []bool{false, true} {
+		diff, err := (&Parser{SingleContextLine: single}).Parse(raw)
+		require.NoError(t, err)
+		hunk := diff.Files[0].Hunks[0]
+
+		orig := hunk.OrigRange.Lines[1]
+		assert.Equal(t, "two", orig.Content)
+		assert.True(t, orig.NoNewline, "single context line %v", single)
+		updated := hunk.NewRange.Lines[1]
+		assert.Equal(t, "two", updated.Content)
+		assert.True(t, updated.NoNewline, "single context line %v", single)
+		assert.Same(t, updated, hunk.WholeRange.Lines[2])
+
+		assert.False(t, hunk.OrigRange.Lines[0].NoNewline)
+		assert.False(t, hunk.NewRange.Lines[0].NoNewline)
+		assert.Equal(t, raw, diff.String())
+	}
+}
+
+func TestIndexFirstFiles(t *testing.T) {
+	diff, err := Parse(`index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1 +1 @@
+-one
++two
+index 0000000..57271b1
+--- /dev/null
++++ b/file2
+@@ -0,0 +1 @@
++new
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	file1 := diff.Files[0]
+	assert.Equal(t, MODIFIED, file1.Mode)
+	assert.Equal(t, "file1", file1.OrigName)
+	assert.Equal(t, "file1", file1.NewName)
+	assert.Equal(t, "504d2a1", file1.OrigHash)
+	assert.Equal(t, "50ccec3", file1.NewHash)
+	assert.Equal(t, "100644", file1.IndexMode)
+	assert.Equal(t, "index 504d2a1..50ccec3 100644\n--- a/file1\n+++ b/file1", file1.DiffHeader)
+	assert.Equal(t, []int{1}, file1.Changed())
+
+	file2 := diff.Files[1]
+	assert.Equal(t, NEW, file2.Mode)
+	assert.Equal(t, "file2", file2.NewName)
+	assert.Equal(t, "57271b1", file2.NewHash)
+	assert.Equal(t, 1, file2.Hunks[0].NewRange.Lines[0].Position)
+}
+
+func TestRejectNUL(t *testing.T) {
+	input := "diff --git a/file b/file\n" +
+		"--- a/file\n" +
+		"+++ b/file\n" +
+		"@@ -1,2 +1,2 @@\n" +
+		" text\n" +
+		"-old\n" +
+		"+new\x00data\n"
+
+	diff, err := Parse(input)
+	require.NoError(t, err)
+	assert.Equal(t, "new\x00data", diff.Files[0].Hunks[0].NewRange.Lines[1].Content)
+
+	_, err = (&Parser{RejectNUL: true}).Parse(input)
+	assert.EqualError(t, err, "line 7 contains a NUL byte")
+
+	_, err = (&Parser{RejectNUL: true}).ParseReader(strings.NewReader(input))
+	assert.EqualError(t, err, "line 7 contains a NUL byte")
+}
+
+func TestRawOffsets(t *testing.T) {
+	byt, err := os.ReadFile("example.diff")
+	require.NoError(t, err)
+	raw := "Some preamble\n\n" + string(byt)
+
+	for _, parse := range []func() (*Diff, error){
+		func() (*Diff, error) { return Parse(raw) },
+		func() (*Diff, error) { return ParseReader(strings.NewReader(raw)) },
+	} {
+		diff, err := parse()
+		require.NoError(t, err)
+
+		for i, f := range diff.Files {
+			segment := diff.Raw[f.RawStart:f.RawEnd]
+			assert.True(t, strings.HasPrefix(segment, f.DiffHeader+"\n"), f.NewName)
+			assert.True(t, strings.HasSuffix(segment, "\n"), f.NewName)
+			if i+1 < len(diff.Files) {
+				assert.Equal(t, diff.Files[i+1].RawStart, f.RawEnd, f.NewName)
+			} else {
+				assert.Equal(t, len(diff.Raw), f.RawEnd)
+			}
+
+			for _, h := range f.Hunks {
+				assert.Equal(t, h.String(), diff.Raw[h.RawStart:h.RawEnd], f.NewName)
+			}
+		}
+	}
+
+	// Headers before the "diff" line are part of the file, and the end of
+	// the last line is the end of the diff, even without a newline.
+	diff, err := Parse(`new file mode 100644
+index 0000000..ce01362
+diff --git a/new.txt b/new.txt
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++new
+\ No newline at end of file`)
+	require.NoError(t, err)
+	f := diff.Files[0]
+	assert.Equal(t, 0, f.RawStart)
+	assert.Equal(t, len(diff.Raw), f.RawEnd)
+	assert.Equal(t, "@@ -0,0 +1 @@\n+new\n\\ No newline at end of file", diff.Raw[f.Hunks[0].RawStart:f.Hunks[0].RawEnd])
+}
+
+func TestTextBinaryFile(t *testing.T) {
+	// "git diff --text" shows binary files as text, so their hunks can have
+	// any control characters in them.
+	raw := "diff --git a/data.bin b/data.bin\n" +
+		"index 1111111..2222222 100644\n" +
+		"--- a/data.bin\n" +
+		"+++ b/data.bin\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" \x7fELF\x02\x01\x01\x00\x00\n" +
+		"-\x1b[31m\x07\x08\x0c\x0b\r\n" +
+		"+\x1b[32m\x01\x02\x03\x04\x1f\r\n" +
+		" \t\x00\x00\x00\xff\xfe\n"
+
+	diff, err := Parse(raw)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+
+	file := diff.Files[0]
+	assert.False(t, file.Binary)
+	require.Len(t, file.Hunks, 1)
+	lines := file.Hunks[0].WholeRange.Lines
+	require.Len(t, lines, 4)
+	assert.Equal(t, "\x7fELF\x02\x01\x01\x00\x00", lines[0].Content)
+	assert.Equal(t, REMOVED, lines[1].Mode)
+	assert.Equal(t, "\x1b[31m\x07\x08\x0c\x0b\r", lines[1].Content)
+	assert.Equal(t, ADDED, lines[2].Mode)
+	assert.Equal(t, "\x1b[32m\x01\x02\x03\x04\x1f\r", lines[2].Content)
+	assert.Equal(t, "\t\x00\x00\x00\xff\xfe", lines[3].Content)
+	assert.Equal(t, raw, diff.String())
+}
+
+func TestMostChangedFiles(t *testing.T) {
+	diff, err := Parse(`--- a/small
++++ b/small
+@@ -1 +1 @@
+-a
++b
+--- a/big
++++ b/big
+@@ -1,2 +1,3 @@
+-a
++b
++c
+ d
+--- a/same
++++ b/same
+@@ -1 +1 @@
+-a
++b
+--- a/none
++++ b/none
+`)
+	require.NoError(t, err)
+
+	names := func(files []*DiffFile) []string {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.NewName)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"big", "same", "small", "none"}, names(diff.MostChangedFiles(10)))
+	assert.Equal(t, []string{"big", "same"}, names(diff.MostChangedFiles(2)))
+	assert.Empty(t, diff.MostChangedFiles(0))
+
+	// The files in the diff stay in their order.
+	assert.Equal(t, []string{"small", "big", "same", "none"}, names(diff.Files))
+}
+
+func TestFormatPatchMessageLikeHeaders(t *testing.T) {
+	diff, err := Parse(`From 482f0c55f27f26a34fc3c30b87a55144f0406f00 Mon Sep 17 00:00:00 2001
+From: Jane Doe <jane@example.com>
+Date: Fri, 16 Oct 2026 09:39:22 +0000
+Subject: [PATCH] Rebuild the index
+
+The index is rebuilt when it's out of date, so:
+index is rebuilt on startup
+new file mode handling is unchanged
+rename from the old API is still to come
+---
+ file1 | 2 +-
+ 1 file changed, 1 insertion(+), 1 deletion(-)
+
+diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-some
++any
+ lines
+-- 
+2.40.0
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+
+	file := diff.Files[0]
+	assert.Equal(t, MODIFIED, file.Mode)
+	assert.Equal(t, "file1", file.OrigName)
+	assert.Equal(t, "504d2a1", file.OrigHash)
+	assert.Equal(t, "diff --git a/file1 b/file1\nindex 504d2a1..50ccec3 100644\n--- a/file1\n+++ b/file1", file.DiffHeader)
+	assert.True(t, strings.HasPrefix(diff.Raw[file.RawStart:], "diff --git a/file1 b/file1\n"))
+}
+
+func TestCombinedDiff(t *testing.T) {
+	diff, err := Parse(`diff --cc file
+index 1111111,2222222..3333333
+--- a/file
++++ b/file
+@@@ -1,2 -1,2 +1,3 @@@
+  one
+ +two
++ three
+diff --git a/other b/other
+index 504d2a1..50ccec3 100644
+--- a/other
++++ b/other
+@@ -1 +1 @@
+-a
++b
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	combined := diff.Files[0]
+	assert.Equal(t, "file", combined.NewName)
+	assert.Empty(t, combined.Hunks)
+
+	other := diff.Files[1]
+	assert.Equal(t, "other", other.NewName)
+	assert.Equal(t, []int{1}, other.Changed())
+	assert.NoError(t, other.CheckPositions())
+}
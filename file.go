@@ -0,0 +1,408 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// HasChanges returns whether the file has any added or removed lines, as
+// opposed to only changes to its metadata, such as its name or mode.
+func (f *DiffFile) HasChanges() bool {
+	for _, h := range f.Hunks {
+		for _, l := range h.WholeRange.Lines {
+			if l.Mode == ADDED || l.Mode == REMOVED {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ChangeKind describes the lines changed in the file: "additions-only" or
+// "deletions-only" if lines were only added or removed, "mixed" if there
+// were both, or "none" if there weren't any.
+func (f *DiffFile) ChangeKind() string {
+	added, removed := false, false
+	for _, h := range f.Hunks {
+		for _, l := range h.WholeRange.Lines {
+			switch l.Mode {
+			case ADDED:
+				added = true
+			case REMOVED:
+				removed = true
+			}
+		}
+	}
+
+	switch {
+	case added && removed:
+		return "mixed"
+	case added:
+		return "additions-only"
+	case removed:
+		return "deletions-only"
+	default:
+		return "none"
+	}
+}
+
+// NewLines returns the lines of the new version of the file that are in the
+// diff, which are its added and unchanged lines, in order.
+func (f *DiffFile) NewLines() []*DiffLine {
+	var lines []*DiffLine
+	for _, h := range f.Hunks {
+		lines = append(lines, h.NewRange.Lines...)
+	}
+	return lines
+}
+
+// OrigLines returns the lines of the original version of the file that are
+// in the diff, which are its removed and unchanged lines, in order.
+func (f *DiffFile) OrigLines() []*DiffLine {
+	var lines []*DiffLine
+	for _, h := range f.Hunks {
+		lines = append(lines, h.OrigRange.Lines...)
+	}
+	return lines
+}
+
+// CoveragePercent returns the percentage of the file's lines that the diff
+// changes, given the total number of lines in the file, which the diff
+// doesn't have. For deleted files, that's the lines removed from the
+// original file, and otherwise it's the lines added to the new file. It's
+// zero if totalLines isn't positive.
+func (f *DiffFile) CoveragePercent(totalLines int) float64 {
+	if totalLines <= 0 {
+		return 0
+	}
+
+	changed := 0
+	for _, h := range f.Hunks {
+		for _, l := range h.WholeRange.Lines {
+			if (f.Mode == DELETED && l.Mode == REMOVED) || (f.Mode != DELETED && l.Mode == ADDED) {
+				changed++
+			}
+		}
+	}
+	percent := 100 * float64(changed) / float64(totalLines)
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// HunkCount returns the number of hunks in the file.
+func (f *DiffFile) HunkCount() int {
+	return len(f.Hunks)
+}
+
+// HunksInRange returns the hunks whose lines in the new version of the file
+// overlap the inclusive range of lines from start to end. A hunk that only
+// removes lines is included if they were removed after a line in the range.
+func (f *DiffFile) HunksInRange(start, end int) []*DiffHunk {
+	var hunks []*DiffHunk
+	for _, h := range f.Hunks {
+		first, last := h.NewRange.first(), h.NewRange.first()+h.NewRange.Length-1
+		if h.NewRange.Length == 0 {
+			first, last = h.NewRange.Start, h.NewRange.Start
+		}
+		if first <= end && last >= start {
+			hunks = append(hunks, h)
+		}
+	}
+	return hunks
+}
+
+// ChangedBounds returns the smallest and largest numbers of added lines in
+// the new version of the file, or ok is false if no lines were added.
+func (f *DiffFile) ChangedBounds() (first, last int, ok bool) {
+	for _, h := range f.Hunks {
+		for _, l := range h.NewRange.Lines {
+			if l.Mode != ADDED {
+				continue
+			}
+			if !ok || l.Number < first {
+				first = l.Number
+			}
+			if !ok || l.Number > last {
+				last = l.Number
+			}
+			ok = true
+		}
+	}
+	return first, last, ok
+}
+
+// Hash returns a SHA-256 hash, in hex, of the file's added and removed
+// lines. It doesn't depend on the unchanged lines around them, or where in
+// the file they are, so it identifies the same change made in different
+// places.
+func (f *DiffFile) Hash() string {
+	h := sha256.New()
+	for _, hunk := range f.Hunks {
+		for _, l := range hunk.WholeRange.Lines {
+			switch l.Mode {
+			case ADDED:
+				h.Write([]byte("+" + l.Content + "\n"))
+			case REMOVED:
+				h.Write([]byte("-" + l.Content + "\n"))
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Extension returns the extension of the file's name, including the leading
+// dot, or an empty string if it doesn't have one. The new name is used,
+// unless the file was deleted.
+func (f *DiffFile) Extension() string {
+	return path.Ext(f.name())
+}
+
+// languages maps file extensions to the language they're usually written in.
+var languages = map[string]string{
+	".c":     "C",
+	".h":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".cxx":   "C++",
+	".hpp":   "C++",
+	".cs":    "C#",
+	".css":   "CSS",
+	".go":    "Go",
+	".html":  "HTML",
+	".htm":   "HTML",
+	".java":  "Java",
+	".js":    "JavaScript",
+	".mjs":   "JavaScript",
+	".cjs":   "JavaScript",
+	".jsx":   "JavaScript",
+	".json":  "JSON",
+	".kt":    "Kotlin",
+	".lua":   "Lua",
+	".md":    "Markdown",
+	".m":     "Objective-C",
+	".php":   "PHP",
+	".pl":    "Perl",
+	".py":    "Python",
+	".rb":    "Ruby",
+	".rs":    "Rust",
+	".scala": "Scala",
+	".sh":    "Shell",
+	".bash":  "Shell",
+	".sql":   "SQL",
+	".swift": "Swift",
+	".toml":  "TOML",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".xml":   "XML",
+	".yaml":  "YAML",
+	".yml":   "YAML",
+}
+
+// filenameLanguages maps well-known file names without a useful extension
+// to their language.
+var filenameLanguages = map[string]string{
+	"Dockerfile":  "Dockerfile",
+	"Makefile":    "Makefile",
+	"GNUmakefile": "Makefile",
+}
+
+// binaryExtensions are the extensions of files that are usually binary,
+// which git only shows as text when they're converted with textconv.
+var binaryExtensions = map[string]bool{
+	".png":   true,
+	".jpg":   true,
+	".jpeg":  true,
+	".gif":   true,
+	".bmp":   true,
+	".ico":   true,
+	".webp":  true,
+	".pdf":   true,
+	".doc":   true,
+	".docx":  true,
+	".xls":   true,
+	".xlsx":  true,
+	".odt":   true,
+	".zip":   true,
+	".gz":    true,
+	".tar":   true,
+	".jar":   true,
+	".exe":   true,
+	".dll":   true,
+	".so":    true,
+	".o":     true,
+	".class": true,
+}
+
+// lfsVersion is the first line of a Git LFS pointer file.
+const lfsVersion = "version https://git-lfs.github.com/spec/v1"
+
+// detectLFS sets LFS if either version of the file is a Git LFS pointer,
+// along with the OIDs of the versions that are.
+func (f *DiffFile) detectLFS() {
+	var origOK, newOK bool
+	f.OrigOID, origOK = lfsPointerOID(f.OrigLines(), func(l *DiffLine) int { return l.OrigNumber })
+	f.NewOID, newOK = lfsPointerOID(f.NewLines(), func(l *DiffLine) int { return l.NewNumber })
+	f.LFS = origOK || newOK
+}
+
+// reLFSOID matches the "oid" line of a Git LFS pointer.
+var reLFSOID = regexp.MustCompile(`^oid (sha256:[0-9a-f]{64})$`)
+
+// reLFSSize matches the "size" line of a Git LFS pointer.
+var reLFSSize = regexp.MustCompile(`^size \d+$`)
+
+// reLFSKey matches any other line of a Git LFS pointer, such as the "ext-"
+// lines that can come between its version and oid lines.
+var reLFSKey = regexp.MustCompile(`^[a-z0-9.-]+ \S`)
+
+// lfsPointerOID returns the OID of the Git LFS pointer made up of lines, and
+// whether it is one: a version line at the start of the file, then "oid"
+// and "size" lines in that order, with every line a key and a value.
+func lfsPointerOID(lines []*DiffLine, number func(*DiffLine) int) (string, bool) {
+	if len(lines) == 0 || lines[0].Content != lfsVersion || number(lines[0]) != 1 {
+		return "", false
+	}
+
+	oid, size := "", false
+	for i, l := range lines[1:] {
+		if number(l) != i+2 {
+			return "", false
+		}
+		switch {
+		case oid == "" && reLFSOID.MatchString(l.Content):
+			oid = reLFSOID.FindStringSubmatch(l.Content)[1]
+		case oid != "" && !size && reLFSSize.MatchString(l.Content):
+			size = true
+		case !reLFSKey.MatchString(l.Content) || strings.HasPrefix(l.Content, "oid ") || strings.HasPrefix(l.Content, "size "):
+			return "", false
+		}
+	}
+	if oid == "" || !size {
+		return "", false
+	}
+	return oid, true
+}
+
+// Language makes a best guess at the language the file is written in from
+// its name, or returns an empty string if it doesn't know.
+func (f *DiffFile) Language() string {
+	if lang, ok := filenameLanguages[path.Base(f.name())]; ok {
+		return lang
+	}
+	return languages[strings.ToLower(f.Extension())]
+}
+
+// BlameHint returns the revision and path to blame to find where the file's
+// changed lines came from, as "commit:path", or an empty string if the
+// file's Commit isn't known. For deleted files, it's the file in the parent
+// of the commit, as "commit^:path".
+func (f *DiffFile) BlameHint() string {
+	if f.Commit == "" {
+		return ""
+	}
+	if f.Mode == DELETED {
+		return f.Commit + "^:" + f.OrigName
+	}
+	return f.Commit + ":" + f.NewName
+}
+
+// RenamedFrom returns the original name of the file if it was renamed or
+// copied, or an empty string otherwise.
+func (f *DiffFile) RenamedFrom() string {
+	if f.Mode != RENAMED && f.Mode != COPIED {
+		return ""
+	}
+	return f.OrigName
+}
+
+// RenamedTo returns the new name of the file if it was renamed or copied,
+// or an empty string otherwise.
+func (f *DiffFile) RenamedTo() string {
+	if f.Mode != RENAMED && f.Mode != COPIED {
+		return ""
+	}
+	return f.NewName
+}
+
+// name returns the name of the file, which is the new name unless the file
+// was deleted.
+func (f *DiffFile) name() string {
+	if f.Mode == DELETED {
+		return f.OrigName
+	}
+	return f.NewName
+}
+
+// CheckPositions checks that the Position and HunkPosition of each line in
+// the file follow GitHub's rules for positions in a diff. The "@@" header of
+// the first hunk is at position zero, and every line after it counts,
+// including the headers of later hunks and "\ No newline at end of file"
+// lines. It returns an error describing the first line that doesn't match.
+func (f *DiffFile) CheckPositions() error {
+	position := 0
+	for i, h := range f.Hunks {
+		if i > 0 {
+			position++
+		}
+		hunkPosition := 0
+		for j, l := range h.WholeRange.Lines {
+			position++
+			hunkPosition++
+			if l.Position != position {
+				return fmt.Errorf("line %d of hunk %d has position %d, expected %d", j+1, i+1, l.Position, position)
+			}
+			if l.HunkPosition != hunkPosition {
+				return fmt.Errorf("line %d of hunk %d has hunk position %d, expected %d", j+1, i+1, l.HunkPosition, hunkPosition)
+			}
+			if l.NoNewline {
+				position++
+				hunkPosition++
+			}
+		}
+	}
+	return nil
+}
+
+// renumberPositions sets the Position and HunkPosition of each line in the
+// file, following the same rules as CheckPositions.
+func (f *DiffFile) renumberPositions() {
+	position := 0
+	for i, h := range f.Hunks {
+		if i > 0 {
+			position++
+		}
+		hunkPosition := 0
+		var unchanged []*DiffLine
+		for _, l := range h.WholeRange.Lines {
+			position++
+			hunkPosition++
+			l.Position, l.HunkPosition = position, hunkPosition
+			if l.Mode == UNCHANGED {
+				unchanged = append(unchanged, l)
+			}
+			if l.NoNewline {
+				position++
+				hunkPosition++
+			}
+		}
+
+		// Unchanged lines can have a separate copy in OrigRange, which
+		// are in the same order.
+		for _, l := range h.OrigRange.Lines {
+			if l.Mode != UNCHANGED || len(unchanged) == 0 {
+				continue
+			}
+			l.Position, l.HunkPosition = unchanged[0].Position, unchanged[0].HunkPosition
+			unchanged = unchanged[1:]
+		}
+	}
+}
@@ -0,0 +1,483 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileHasChanges(t *testing.T) {
+	diff := setup(t)
+	for i, expected := range []bool{true, true, true, true, true, true, false, false, false} {
+		assert.Equal(t, expected, diff.Files[i].HasChanges(), diff.Files[i].NewName)
+	}
+	assert.False(t, diff.IsEmpty())
+
+	diff, err := Parse(`diff --git a/script b/script
+old mode 100644
+new mode 100755
+diff --git a/old b/new
+similarity index 100%
+rename from old
+rename to new
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+	assert.False(t, diff.Files[0].HasChanges())
+	assert.False(t, diff.Files[1].HasChanges())
+	assert.True(t, diff.IsEmpty())
+}
+
+func TestFileChangeKind(t *testing.T) {
+	diff := setup(t)
+
+	assert.Equal(t, "mixed", diff.Files[0].ChangeKind())
+	assert.Equal(t, "deletions-only", diff.Files[1].ChangeKind())
+	assert.Equal(t, "additions-only", diff.File("newname").ChangeKind())
+	assert.Equal(t, "none", diff.File("newEmpty").ChangeKind())
+	assert.Equal(t, "none", (&DiffFile{}).ChangeKind())
+}
+
+func TestFileChangedBounds(t *testing.T) {
+	diff, err := Parse(`diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -3,3 +3,4 @@ import "fmt"
+ 
++// main says hello.
+ func main() {
+ 	fmt.Println("hello")
+@@ -20,3 +21,3 @@ func helper() {
+ 	a := 1
+-	b := 2
++	b := 3
+ 	return a + b
+`)
+	require.NoError(t, err)
+
+	first, last, ok := diff.Files[0].ChangedBounds()
+	assert.True(t, ok)
+	assert.Equal(t, 4, first)
+	assert.Equal(t, 22, last)
+
+	// Only removed lines.
+	_, _, ok = setup(t).Files[1].ChangedBounds()
+	assert.False(t, ok)
+}
+
+func TestFileNewOrigLines(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+--- a/file
++++ b/file
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+@@ -10,2 +10,3 @@
+ ten
++ten and a half
+ eleven
+`)
+	require.NoError(t, err)
+	file := diff.Files[0]
+
+	var newLines []string
+	var newNumbers []int
+	for _, l := range file.NewLines() {
+		assert.NotEqual(t, REMOVED, l.Mode)
+		newLines = append(newLines, l.Content)
+		newNumbers = append(newNumbers, l.NewNumber)
+	}
+	assert.Equal(t, []string{"one", "TWO", "three", "ten", "ten and a half", "eleven"}, newLines)
+	assert.Equal(t, []int{1, 2, 3, 10, 11, 12}, newNumbers)
+
+	var origLines []string
+	var origNumbers []int
+	for _, l := range file.OrigLines() {
+		assert.NotEqual(t, ADDED, l.Mode)
+		origLines = append(origLines, l.Content)
+		origNumbers = append(origNumbers, l.OrigNumber)
+	}
+	assert.Equal(t, []string{"one", "two", "three", "ten", "eleven"}, origLines)
+	assert.Equal(t, []int{1, 2, 3, 10, 11}, origNumbers)
+
+	// Each hunk's part of the lines is its snippet.
+	assert.Equal(t, file.Hunks[1].NewSnippet(), newLines[3:])
+	assert.Equal(t, file.Hunks[1].OrigSnippet(), origLines[3:])
+}
+
+func TestFileCoveragePercent(t *testing.T) {
+	diff := setup(t)
+
+	// One line added to a four line file.
+	assert.Equal(t, 25.0, diff.Files[0].CoveragePercent(4))
+	assert.Equal(t, 10.0, diff.Files[0].CoveragePercent(10))
+	// All four lines of a deleted file.
+	assert.Equal(t, 100.0, diff.Files[1].CoveragePercent(4))
+	assert.Equal(t, 50.0, diff.File("newname").CoveragePercent(8))
+
+	assert.Equal(t, 100.0, diff.Files[0].CoveragePercent(1))
+	assert.Equal(t, 0.0, diff.Files[0].CoveragePercent(0))
+	assert.Equal(t, 0.0, diff.File("newEmpty").CoveragePercent(10))
+}
+
+func TestFileHunksInRange(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+--- a/file
++++ b/file
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+@@ -10,3 +10,4 @@
+ ten
++ten and a half
+ eleven
+ twelve
+@@ -20,2 +21,0 @@
+-twenty
+-twenty-one
+`)
+	require.NoError(t, err)
+	file := diff.Files[0]
+	hunks := file.Hunks
+
+	for _, tc := range []struct {
+		start, end int
+		expected   []*DiffHunk
+	}{
+		{start: 1, end: 1, expected: hunks[:1]},
+		{start: 3, end: 10, expected: hunks[:2]},
+		{start: 4, end: 9},
+		{start: 13, end: 13, expected: hunks[1:2]},
+		{start: 14, end: 20},
+		{start: 14, end: 21, expected: hunks[2:]},
+		{start: 1, end: 100, expected: hunks},
+	} {
+		assert.Equal(t, tc.expected, file.HunksInRange(tc.start, tc.end), "%d-%d", tc.start, tc.end)
+	}
+}
+
+func TestFileLanguage(t *testing.T) {
+	for _, tc := range []struct {
+		file      DiffFile
+		extension string
+		language  string
+	}{
+		{
+			file:      DiffFile{Mode: MODIFIED, OrigName: "main.go", NewName: "main.go"},
+			extension: ".go",
+			language:  "Go",
+		},
+		{
+			file:      DiffFile{Mode: RENAMED, OrigName: "script", NewName: "lib/script.PY"},
+			extension: ".PY",
+			language:  "Python",
+		},
+		{
+			file:      DiffFile{Mode: DELETED, OrigName: "old.py", NewName: "old.py"},
+			extension: ".py",
+			language:  "Python",
+		},
+		{
+			file:      DiffFile{Mode: NEW, OrigName: "LICENSE", NewName: "LICENSE"},
+			extension: "",
+			language:  "",
+		},
+		{
+			file:      DiffFile{Mode: NEW, OrigName: "build/Dockerfile", NewName: "build/Dockerfile"},
+			extension: "",
+			language:  "Dockerfile",
+		},
+	} {
+		t.Run(tc.file.NewName, func(t *testing.T) {
+			assert.Equal(t, tc.extension, tc.file.Extension())
+			assert.Equal(t, tc.language, tc.file.Language())
+		})
+	}
+}
+
+func TestFileHash(t *testing.T) {
+	parse := func(s string) *DiffFile {
+		diff, err := Parse(s)
+		require.NoError(t, err)
+		require.Len(t, diff.Files, 1)
+		return diff.Files[0]
+	}
+
+	a := parse(`diff --git a/file b/file
+index 504d2a1..50ccec3 100644
+--- a/file
++++ b/file
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+`)
+	b := parse(`diff --git a/file b/file
+index 1e3a9c0..4d5e6f7 100644
+--- a/file
++++ b/file
+@@ -10,5 +10,5 @@ func main() {
+ eight
+ nine
+-two
++TWO
+ ten
+ eleven
+`)
+	c := parse(`diff --git a/file b/file
+index 504d2a1..50ccec3 100644
+--- a/file
++++ b/file
+@@ -1,3 +1,3 @@
+ one
+-two
++TOO
+ three
+`)
+
+	assert.Len(t, a.Hash(), 64)
+	assert.Equal(t, a.Hash(), b.Hash())
+	assert.NotEqual(t, a.Hash(), c.Hash())
+}
+
+func TestFileCheckPositions(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+index 504d2a1..50ccec3 100644
+--- a/file
++++ b/file
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+@@ -10,2 +10,3 @@
+ ten
++ten and a half
+ eleven
+@@ -20,1 +21,1 @@
+-twenty
+\ No newline at end of file
++TWENTY
+\ No newline at end of file
+`)
+	require.NoError(t, err)
+	file := diff.Files[0]
+	require.NoError(t, file.CheckPositions())
+	for _, f := range setup(t).Files {
+		require.NoError(t, f.CheckPositions(), f.NewName)
+	}
+
+	last := file.Hunks[2].WholeRange.Lines[1]
+	assert.Equal(t, "TWENTY", last.Content)
+	assert.True(t, last.NoNewline)
+	assert.Equal(t, 11, last.Position)
+
+	last.Position = 10
+	assert.EqualError(t, file.CheckPositions(), "line 2 of hunk 3 has position 10, expected 11")
+	last.Position = 11
+
+	file.Hunks[1].WholeRange.Lines[0].HunkPosition = 5
+	assert.EqualError(t, file.CheckPositions(), "line 1 of hunk 2 has hunk position 5, expected 1")
+}
+
+func TestFileLFS(t *testing.T) {
+	diff, err := Parse(`diff --git a/assets/video.mp4 b/assets/video.mp4
+index 7c1a2b3..9d8e7f6 100644
+--- a/assets/video.mp4
++++ b/assets/video.mp4
+@@ -1,3 +1,3 @@
+ version https://git-lfs.github.com/spec/v1
+-oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+-size 12345
++oid sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824
++size 67890
+diff --git a/assets/new.bin b/assets/new.bin
+new file mode 100644
+index 0000000..1a2b3c4
+--- /dev/null
++++ b/assets/new.bin
+@@ -0,0 +1,3 @@
++version https://git-lfs.github.com/spec/v1
++oid sha256:486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7
++size 42
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1,1 +1,1 @@
+-oid sha256:not a pointer
++oid sha256:still not a pointer
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	video := diff.Files[0]
+	assert.True(t, video.LFS)
+	assert.Equal(t, "sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", video.OrigOID)
+	assert.Equal(t, "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", video.NewOID)
+
+	added := diff.Files[1]
+	assert.True(t, added.LFS)
+	assert.Equal(t, "", added.OrigOID)
+	assert.Equal(t, "sha256:486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7", added.NewOID)
+
+	readme := diff.Files[2]
+	assert.False(t, readme.LFS)
+	assert.Equal(t, "", readme.NewOID)
+}
+
+func TestFileLFSBinaryExtension(t *testing.T) {
+	diff, err := Parse(`diff --git a/logo.png b/logo.png
+--- a/logo.png
++++ b/logo.png
+@@ -1,3 +1,3 @@
+ version https://git-lfs.github.com/spec/v1
+-oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+-size 12345
++oid sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824
++size 67890
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+
+	// The pointer is the text that's in git, not the output of a textconv
+	// filter.
+	assert.True(t, diff.Files[0].LFS)
+	assert.False(t, diff.Files[0].TextConv)
+}
+
+func TestFileLFSNotPointer(t *testing.T) {
+	diff, err := Parse(`diff --git a/docs/lfs.md b/docs/lfs.md
+--- a/docs/lfs.md
++++ b/docs/lfs.md
+@@ -3,3 +3,4 @@ A pointer file looks like:
+ version https://git-lfs.github.com/spec/v1
+ oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+ size 12345
++which is all git stores.
+diff --git a/notes.txt b/notes.txt
+--- a/notes.txt
++++ b/notes.txt
+@@ -1,2 +1,3 @@
+ version https://git-lfs.github.com/spec/v1
+-is the spec
++is the spec,
++see oid sha256:abc
+diff --git a/assets/image.png b/assets/image.png
+--- a/assets/image.png
++++ b/assets/image.png
+@@ -1,3 +1,1 @@
+-version https://git-lfs.github.com/spec/v1
+-oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+-size 12345
++not a pointer any more
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	assert.False(t, diff.Files[0].LFS)
+	assert.False(t, diff.Files[1].LFS)
+	assert.Equal(t, "", diff.Files[1].NewOID)
+
+	image := diff.Files[2]
+	assert.True(t, image.LFS)
+	assert.Equal(t, "sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", image.OrigOID)
+	assert.Equal(t, "", image.NewOID)
+}
+
+func TestRenumberPositions(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+--- a/file
++++ b/file
+@@ -1,3 +1,3 @@
+-one
++ONE
+ two
+-three
++THREE
+@@ -10,2 +10,3 @@
+ ten
++ten and a half
+ eleven
+`)
+	require.NoError(t, err)
+	file := diff.Files[0]
+
+	// Keep only the change to "three", which leaves a gap in the positions.
+	sub, err := file.Hunks[0].Subset(func(l *DiffLine) bool {
+		return l.Content == "three" || l.Content == "THREE"
+	})
+	require.NoError(t, err)
+	file.Hunks[0] = sub
+	require.Error(t, file.CheckPositions())
+
+	diff.RenumberPositions()
+	require.NoError(t, file.CheckPositions())
+
+	hunk := file.Hunks[0]
+	assert.Equal(t, []string{"one", "two", "three", "THREE"}, []string{
+		hunk.WholeRange.Lines[0].Content,
+		hunk.WholeRange.Lines[1].Content,
+		hunk.WholeRange.Lines[2].Content,
+		hunk.WholeRange.Lines[3].Content,
+	})
+	for i, l := range hunk.OrigRange.Lines {
+		assert.Equal(t, i+1, l.Position, l.Content)
+		assert.Equal(t, i+1, l.HunkPosition, l.Content)
+	}
+	assert.Equal(t, 6, file.Hunks[1].WholeRange.Lines[0].Position)
+	assert.Equal(t, 1, file.Hunks[1].WholeRange.Lines[0].HunkPosition)
+}
+
+func TestFileRenamed(t *testing.T) {
+	diff := setup(t)
+
+	renamed := diff.File("new")
+	assert.Equal(t, "old", renamed.RenamedFrom())
+	assert.Equal(t, "new", renamed.RenamedTo())
+
+	assert.Equal(t, "", diff.Files[0].RenamedFrom())
+	assert.Equal(t, "", diff.Files[0].RenamedTo())
+
+	copied := &DiffFile{Mode: COPIED, OrigName: "template.txt", NewName: "copy.txt"}
+	assert.Equal(t, "template.txt", copied.RenamedFrom())
+	assert.Equal(t, "copy.txt", copied.RenamedTo())
+}
+
+func TestFileBlameHint(t *testing.T) {
+	diff, err := Parse(`commit 482f0c55f27f26a34fc3c30b87a55144f0406f00
+Author: Jane Doe <jane@example.com>
+
+    Move things around
+
+diff --git a/src/main.go b/src/main.go
+index 504d2a1..50ccec3 100644
+--- a/src/main.go
++++ b/src/main.go
+@@ -1,1 +1,1 @@
+-package old
++package main
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index c0dafd8..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1,1 +0,0 @@
+-old
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	assert.Equal(t, "482f0c55f27f26a34fc3c30b87a55144f0406f00", diff.Files[0].Commit)
+	assert.Equal(t, "482f0c55f27f26a34fc3c30b87a55144f0406f00:src/main.go", diff.Files[0].BlameHint())
+	assert.Equal(t, "482f0c55f27f26a34fc3c30b87a55144f0406f00^:old.txt", diff.Files[1].BlameHint())
+
+	assert.Equal(t, "", setup(t).Files[0].BlameHint())
+}
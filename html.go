@@ -0,0 +1,52 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// HTML renders the diff as an HTML table, with a row for each line. Rows
+// for lines have the class "added", "removed" or "context", and cells with
+// the old and new line numbers, followed by the content. Each file starts
+// with a row with the class "file", and each hunk with a row with the class
+// "hunk". The lines hidden by Collapse are shown by a row with the class
+// "collapsed".
+func (d *Diff) HTML() string {
+	var sb strings.Builder
+
+	sb.WriteString("<table class=\"diff\">\n")
+	for _, f := range d.Files {
+		fmt.Fprintf(&sb, "<tr class=\"file\"><th colspan=\"3\">%s</th></tr>\n", html.EscapeString(statName(f)))
+		for _, h := range f.Hunks {
+			header := fmt.Sprintf("@@ -%s +%s @@", h.OrigRange.String(), h.NewRange.String())
+			if h.HunkHeader != "" {
+				header += " " + h.HunkHeader
+			}
+			fmt.Fprintf(&sb, "<tr class=\"hunk\"><td colspan=\"3\">%s</td></tr>\n", html.EscapeString(header))
+			for _, l := range h.WholeRange.Lines {
+				if l.Collapsed > 0 {
+					fmt.Fprintf(&sb, "<tr class=\"collapsed\"><td colspan=\"3\">%d hidden %s</td></tr>\n", l.Collapsed, plural(l.Collapsed, "line", "lines"))
+					continue
+				}
+				class, orig, updated := "context", "", ""
+				switch l.Mode {
+				case ADDED:
+					class, updated = "added", fmt.Sprint(l.NewNumber)
+				case REMOVED:
+					class, orig = "removed", fmt.Sprint(l.OrigNumber)
+				default:
+					orig, updated = fmt.Sprint(l.OrigNumber), fmt.Sprint(l.NewNumber)
+				}
+				fmt.Fprintf(&sb, "<tr class=\"%s\"><td class=\"old\">%s</td><td class=\"new\">%s</td><td class=\"content\">%s</td></tr>\n",
+					class, orig, updated, html.EscapeString(l.Content))
+			}
+		}
+	}
+	sb.WriteString("</table>\n")
+
+	return sb.String()
+}
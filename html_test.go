@@ -0,0 +1,35 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTML(t *testing.T) {
+	diff, err := Parse(`diff --git a/page.html b/page.html
+index 1111111..2222222 100644
+--- a/page.html
++++ b/page.html
+@@ -1,3 +1,3 @@ <body>
+ <p>hello</p>
+-<p>old & busted</p>
++<script>alert("hi")</script>
+ <p>goodbye</p>
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, `<table class="diff">
+<tr class="file"><th colspan="3">page.html</th></tr>
+<tr class="hunk"><td colspan="3">@@ -1,3 +1,3 @@ &lt;body&gt;</td></tr>
+<tr class="context"><td class="old">1</td><td class="new">1</td><td class="content">&lt;p&gt;hello&lt;/p&gt;</td></tr>
+<tr class="removed"><td class="old">2</td><td class="new"></td><td class="content">&lt;p&gt;old &amp; busted&lt;/p&gt;</td></tr>
+<tr class="added"><td class="old"></td><td class="new">2</td><td class="content">&lt;script&gt;alert(&#34;hi&#34;)&lt;/script&gt;</td></tr>
+<tr class="context"><td class="old">3</td><td class="new">3</td><td class="content">&lt;p&gt;goodbye&lt;/p&gt;</td></tr>
+</table>
+`, diff.HTML())
+}
@@ -0,0 +1,341 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Section returns the section heading from the hunk's header, such as the
+// function the hunk is in, without any leading whitespace. HunkHeader has
+// the heading exactly as it appears in the diff.
+func (hunk *DiffHunk) Section() string {
+	return strings.TrimLeft(hunk.HunkHeader, " \t")
+}
+
+// OrigRangeString returns the hunk's original range as it appears in its
+// header, such as "10,5".
+func (hunk *DiffHunk) OrigRangeString() string {
+	return hunk.OrigRange.String()
+}
+
+// NewRangeString returns the hunk's new range as it appears in its header,
+// such as "10,5".
+func (hunk *DiffHunk) NewRangeString() string {
+	return hunk.NewRange.String()
+}
+
+// Subset returns a copy of the hunk with only the changes for which selected
+// returns true, like staging part of a hunk with "git add -p". Unselected
+// added lines are dropped and unselected removed lines become context, so
+// that the result still applies to the original file. Line numbers and
+// ranges are recomputed, while Positions are kept from the original hunk.
+func (hunk *DiffHunk) Subset(selected func(*DiffLine) bool) (*DiffHunk, error) {
+	sub := &DiffHunk{
+		HunkHeader: hunk.HunkHeader,
+	}
+	origNumber, newNumber := hunk.OrigRange.first(), hunk.NewRange.first()
+
+	// Unselected removed lines are still in the new file, so they go after
+	// the selected added lines that replace the lines before them, but
+	// before any selected removed line that comes after them.
+	var held []DiffLine
+	flush := func() {
+		for _, line := range held {
+			sub.addLine(line, &origNumber, &newNumber, false)
+		}
+		held = nil
+	}
+
+	changed, inAdded := false, false
+	for _, l := range hunk.WholeRange.Lines {
+		line := *l
+		switch l.Mode {
+		case ADDED:
+			inAdded = true
+			if !selected(l) {
+				continue
+			}
+			changed = true
+		case REMOVED:
+			if inAdded {
+				flush()
+				inAdded = false
+			}
+			if !selected(l) {
+				line.Mode = UNCHANGED
+				held = append(held, line)
+				continue
+			}
+			flush()
+			changed = true
+		default:
+			flush()
+			inAdded = false
+		}
+		sub.addLine(line, &origNumber, &newNumber, false)
+	}
+	flush()
+	if !changed {
+		return nil, errors.New("no changes selected in hunk")
+	}
+
+	sub.OrigRange.resize(hunk.OrigRange.first(), len(sub.OrigRange.Lines))
+	sub.NewRange.resize(hunk.NewRange.first(), len(sub.NewRange.Lines))
+	return sub, nil
+}
+
+// ChangedLinesWithPositions returns the added and removed lines in the hunk,
+// in the order they appear in the diff. The lines keep their Position, so
+// they can still be used to comment on the diff.
+func (hunk *DiffHunk) ChangedLinesWithPositions() []*DiffLine {
+	var lines []*DiffLine
+	for _, l := range hunk.WholeRange.Lines {
+		if l.Mode == ADDED || l.Mode == REMOVED {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// AddedText returns the content of the hunk's added lines, joined with
+// newlines.
+func (hunk *DiffHunk) AddedText() string {
+	return hunk.joinLines(ADDED)
+}
+
+// RemovedText returns the content of the hunk's removed lines, joined with
+// newlines.
+func (hunk *DiffHunk) RemovedText() string {
+	return hunk.joinLines(REMOVED)
+}
+
+func (hunk *DiffHunk) joinLines(mode DiffLineMode) string {
+	var lines []string
+	for _, l := range hunk.WholeRange.Lines {
+		if l.Mode == mode {
+			lines = append(lines, l.Content)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NewSnippet returns the lines of the new file that the hunk covers, which
+// are its unchanged and added lines, starting at NewRange.Start.
+func (hunk *DiffHunk) NewSnippet() []string {
+	return hunk.NewRange.contents()
+}
+
+// OrigSnippet returns the lines of the original file that the hunk covers,
+// which are its unchanged and removed lines, starting at OrigRange.Start.
+func (hunk *DiffHunk) OrigSnippet() []string {
+	return hunk.OrigRange.contents()
+}
+
+func (r *DiffRange) contents() []string {
+	lines := make([]string, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		lines = append(lines, l.Content)
+	}
+	return lines
+}
+
+// LineAt returns the line in the range with the line number n, or nil if
+// there isn't one. Unchanged lines are found by their number in the file
+// the range is from, even when they're shared with the other side because
+// of Parser.SingleContextLine.
+func (r *DiffRange) LineAt(n int) *DiffLine {
+	orig := r.isOrig()
+	for _, l := range r.Lines {
+		number := l.Number
+		switch {
+		case orig && l.OrigNumber != 0:
+			number = l.OrigNumber
+		case !orig && l.NewNumber != 0:
+			number = l.NewNumber
+		}
+		if number == n {
+			return l
+		}
+	}
+	return nil
+}
+
+// isOrig reports whether the range is from the original file, which has
+// removed lines, rather than the new file, which has added lines. A range of
+// only unchanged lines is from the original file if its first line's number
+// there is where the range starts, and not its number in the new file.
+func (r *DiffRange) isOrig() bool {
+	for _, l := range r.Lines {
+		switch l.Mode {
+		case REMOVED:
+			return true
+		case ADDED:
+			return false
+		}
+	}
+	if len(r.Lines) == 0 {
+		return false
+	}
+	first := r.Lines[0]
+	return first.OrigNumber == r.first() && first.NewNumber != r.first()
+}
+
+// pairSimilarity is the LineSimilarity above which PairChanges pairs a
+// removed line with an added one.
+const pairSimilarity = 0.5
+
+// PairChanges pairs each removed line with an added line from the block of
+// added lines right after it, if they're similar enough that the added line
+// is probably an edited version of the removed one, such as for showing
+// which characters changed. Pairs are chosen greedily, taking the most
+// similar added line for each removed line in turn, and are kept in order.
+func (hunk *DiffHunk) PairChanges() [][2]*DiffLine {
+	var pairs [][2]*DiffLine
+	for _, block := range hunk.replacements() {
+		removed, added := block[0], block[1]
+		next := 0
+		for _, r := range removed {
+			best, bestScore := -1, pairSimilarity
+			for j := next; j < len(added); j++ {
+				// On a tie, the earliest line wins, leaving the later ones
+				// for the removed lines after this one.
+				score := LineSimilarity(r.Content, added[j].Content)
+				if score > bestScore || (best < 0 && score == bestScore) {
+					best, bestScore = j, score
+					if score == 1 {
+						break
+					}
+				}
+			}
+			if best >= 0 {
+				pairs = append(pairs, [2]*DiffLine{r, added[best]})
+				next = best + 1
+			}
+		}
+	}
+	return pairs
+}
+
+// replacements returns each run of removed lines in the hunk, with the run
+// of added lines right after it, which may be empty.
+func (hunk *DiffHunk) replacements() [][2][]*DiffLine {
+	var blocks [][2][]*DiffLine
+	lines := hunk.WholeRange.Lines
+	for i := 0; i < len(lines); {
+		if lines[i].Mode != REMOVED {
+			i++
+			continue
+		}
+		removedStart := i
+		for i < len(lines) && lines[i].Mode == REMOVED {
+			i++
+		}
+		addedStart := i
+		for i < len(lines) && lines[i].Mode == ADDED {
+			i++
+		}
+		blocks = append(blocks, [2][]*DiffLine{lines[removedStart:addedStart], lines[addedStart:i]})
+	}
+	return blocks
+}
+
+// maxLevenshteinCells limits the work LineSimilarity does on long lines,
+// such as minified code, as the number of characters in one line times the
+// number in the other, after their common prefix and suffix.
+const maxLevenshteinCells = 1 << 20
+
+// LineSimilarity returns how similar two lines are, from 0 if they have
+// nothing in common to 1 if they're the same. It's one minus the Levenshtein
+// distance between them, in characters, divided by the length of the longer
+// line. For very long lines whose differences aren't only in a short part
+// of them, the parts that differ are taken to be completely different, so
+// the similarity can be lower than the real one.
+func LineSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	// The common prefix and suffix don't add to the distance.
+	for len(ra) > 0 && len(rb) > 0 && ra[0] == rb[0] {
+		ra, rb = ra[1:], rb[1:]
+	}
+	for len(ra) > 0 && len(rb) > 0 && ra[len(ra)-1] == rb[len(rb)-1] {
+		ra, rb = ra[:len(ra)-1], rb[:len(rb)-1]
+	}
+
+	var distance int
+	if len(ra)*len(rb) > maxLevenshteinCells {
+		distance = len(ra)
+		if len(rb) > distance {
+			distance = len(rb)
+		}
+	} else {
+		distance = levenshtein(ra, rb)
+	}
+	return 1 - float64(distance)/float64(longest)
+}
+
+// levenshtein returns the number of insertions, deletions and substitutions
+// needed to turn a into b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// String returns the range as it appears in a hunk header, "start,length",
+// leaving out the length when it's 1 like git does.
+func (r DiffRange) String() string {
+	if r.Length == 1 {
+		return strconv.Itoa(r.Start)
+	}
+	return strconv.Itoa(r.Start) + "," + strconv.Itoa(r.Length)
+}
+
+// first returns the number of the first line in the range. Empty ranges
+// start at the line before where they would be, so this is one past Start.
+func (r *DiffRange) first() int {
+	if r.Length == 0 {
+		return r.Start + 1
+	}
+	return r.Start
+}
+
+// resize sets the range to length lines from first, following the
+// convention for the Start of empty ranges.
+func (r *DiffRange) resize(first int, length int) {
+	r.Length = length
+	r.Start = first
+	if length == 0 {
+		r.Start--
+	}
+}
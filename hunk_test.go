@@ -0,0 +1,383 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHunkSubset(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+index 504d2a1..50ccec3 100644
+--- a/file
++++ b/file
+@@ -10,4 +10,4 @@ func main() {
+ one
+-two
+-three
++TWO
++THREE
+ four
+`)
+	require.NoError(t, err)
+	hunk := diff.Files[0].Hunks[0]
+
+	// Stage only the change of "two" to "TWO".
+	sub, err := hunk.Subset(func(l *DiffLine) bool {
+		return l.Content == "two" || l.Content == "TWO"
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "func main() {", sub.HunkHeader)
+	assert.Equal(t, 10, sub.OrigRange.Start)
+	assert.Equal(t, 4, sub.OrigRange.Length)
+	assert.Equal(t, 10, sub.NewRange.Start)
+	assert.Equal(t, 4, sub.NewRange.Length)
+
+	var lines []DiffLine
+	for _, l := range sub.WholeRange.Lines {
+		lines = append(lines, DiffLine{Mode: l.Mode, Number: l.Number, Content: l.Content})
+	}
+	assert.Equal(t, []DiffLine{
+		{Mode: UNCHANGED, Number: 10, Content: "one"},
+		{Mode: REMOVED, Number: 11, Content: "two"},
+		{Mode: ADDED, Number: 11, Content: "TWO"},
+		{Mode: UNCHANGED, Number: 12, Content: "three"},
+		{Mode: UNCHANGED, Number: 13, Content: "four"},
+	}, lines)
+
+	// The original hunk is untouched.
+	assert.Len(t, hunk.WholeRange.Lines, 6)
+
+	_, err = hunk.Subset(func(l *DiffLine) bool { return false })
+	assert.Error(t, err)
+}
+
+func TestHunkSubsetRemovedOrder(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+--- a/file
++++ b/file
+@@ -1,3 +1,3 @@
+-a
+-b
++c
++d
+ x
+`)
+	require.NoError(t, err)
+
+	// "a" is kept as context, and has to stay before "b".
+	sub, err := diff.Files[0].Hunks[0].Subset(func(l *DiffLine) bool {
+		return l.Content == "b" || l.Content == "c"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "@@ -1,3 +1,3 @@\n a\n-b\n+c\n x\n", sub.String())
+}
+
+func TestHunkSubsetEmptySide(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+deleted file mode 100644
+index c0dafd8..0000000
+--- a/file
++++ /dev/null
+@@ -1,3 +0,0 @@
+-one
+-two
+-three
+`)
+	require.NoError(t, err)
+	hunk := diff.Files[0].Hunks[0]
+
+	// Only remove the last line.
+	sub, err := hunk.Subset(func(l *DiffLine) bool {
+		return l.Content == "three"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, sub.OrigRange.Start)
+	assert.Equal(t, 3, sub.OrigRange.Length)
+	assert.Equal(t, 1, sub.NewRange.Start)
+	assert.Equal(t, 2, sub.NewRange.Length)
+}
+
+func TestHunkAddedRemovedText(t *testing.T) {
+	diff, err := Parse(`diff --git a/hello.txt b/hello.txt
+--- a/hello.txt
++++ b/hello.txt
+@@ -1,4 +1,4 @@
+-hello
++hello,
++world
+ and
+-goodbye
+-world
++bye
+`)
+	require.NoError(t, err)
+	hunk := diff.Files[0].Hunks[0]
+
+	assert.Equal(t, "hello,\nworld\nbye", hunk.AddedText())
+	assert.Equal(t, "hello\ngoodbye\nworld", hunk.RemovedText())
+
+	hunk = setup(t).Files[1].Hunks[0]
+	assert.Equal(t, "", hunk.AddedText())
+}
+
+func TestHunkSnippets(t *testing.T) {
+	hunk := setup(t).Files[0].Hunks[0]
+
+	assert.Equal(t, []string{"add a line", "some", "lines", "file1"}, hunk.NewSnippet())
+	assert.Equal(t, []string{"some", "lines", "in", "file1"}, hunk.OrigSnippet())
+
+	hunk = setup(t).Files[1].Hunks[0]
+	assert.Empty(t, hunk.NewSnippet())
+	assert.NotEmpty(t, hunk.OrigSnippet())
+}
+
+func TestRangeLineAt(t *testing.T) {
+	diff := setup(t)
+	hunk := diff.Files[0].Hunks[0]
+
+	line := hunk.OrigRange.LineAt(3)
+	require.NotNil(t, line)
+	assert.Equal(t, REMOVED, line.Mode)
+	assert.Equal(t, "in", line.Content)
+
+	line = hunk.NewRange.LineAt(1)
+	require.NotNil(t, line)
+	assert.Equal(t, ADDED, line.Mode)
+	assert.Equal(t, "add a line", line.Content)
+
+	// The same line number can refer to different lines on each side.
+	assert.Equal(t, "file1", hunk.OrigRange.LineAt(4).Content)
+	assert.Equal(t, "file1", hunk.NewRange.LineAt(4).Content)
+	assert.Equal(t, "some", hunk.OrigRange.LineAt(1).Content)
+
+	assert.Nil(t, hunk.NewRange.LineAt(5))
+	assert.Nil(t, hunk.OrigRange.LineAt(0))
+}
+
+func TestRangeLineAtSingleContextLine(t *testing.T) {
+	diff, err := (&Parser{SingleContextLine: true}).Parse(`--- a/file
++++ b/file
+@@ -1,3 +1,4 @@
++new
+ one
+ two
+ three
+@@ -10,2 +11,2 @@
+ ten
+ eleven
+`)
+	require.NoError(t, err)
+
+	hunk := diff.Files[0].Hunks[0]
+	assert.Equal(t, "one", hunk.OrigRange.LineAt(1).Content)
+	assert.Equal(t, "three", hunk.OrigRange.LineAt(3).Content)
+	assert.Nil(t, hunk.OrigRange.LineAt(4))
+	assert.Equal(t, "new", hunk.NewRange.LineAt(1).Content)
+	assert.Equal(t, "three", hunk.NewRange.LineAt(4).Content)
+
+	// Without any changes, the side is told from where the range starts.
+	hunk = diff.Files[0].Hunks[1]
+	assert.Equal(t, "ten", hunk.OrigRange.LineAt(10).Content)
+	assert.Equal(t, "ten", hunk.NewRange.LineAt(11).Content)
+	assert.Nil(t, hunk.OrigRange.LineAt(12))
+}
+
+func TestRangeString(t *testing.T) {
+	assert.Equal(t, "3,4", DiffRange{Start: 3, Length: 4}.String())
+	assert.Equal(t, "7", DiffRange{Start: 7, Length: 1}.String())
+	assert.Equal(t, "0,0", DiffRange{Start: 0, Length: 0}.String())
+	assert.Equal(t, "5,0", DiffRange{Start: 5, Length: 0}.String())
+
+	hunk := setup(t).Files[0].Hunks[0]
+	assert.Equal(t, "1,4", hunk.OrigRange.String())
+	assert.Equal(t, "1,4", hunk.NewRange.String())
+}
+
+func TestHunkRangeStrings(t *testing.T) {
+	hunk := &DiffHunk{
+		OrigRange: DiffRange{Start: 10, Length: 5},
+		NewRange:  DiffRange{Start: 12, Length: 1},
+	}
+	assert.Equal(t, "10,5", hunk.OrigRangeString())
+	assert.Equal(t, "12", hunk.NewRangeString())
+
+	hunk = setup(t).Files[0].Hunks[0]
+	assert.Equal(t, "1,4", hunk.OrigRangeString())
+	assert.Equal(t, "1,4", hunk.NewRangeString())
+}
+
+func TestHunkChangedLinesWithPositions(t *testing.T) {
+	diff := setup(t)
+
+	lines := diff.Files[0].Hunks[0].ChangedLinesWithPositions()
+	require.Len(t, lines, 2)
+	assert.Equal(t, DiffLine{Mode: ADDED, Number: 1, Content: "add a line", Position: 1, NewNumber: 1, HunkPosition: 1}, *lines[0])
+	assert.Equal(t, DiffLine{Mode: REMOVED, Number: 3, Content: "in", Position: 4, OrigNumber: 3, HunkPosition: 4}, *lines[1])
+}
+
+func TestHunkLengthEmpty(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+index 504d2a1..50ccec3 100644
+--- a/file
++++ b/file
+@@ -1,0 +1,0 @@
+@@ -3,2 +3,2 @@
+-three
++THREE
+ four
+@@ -9,2 +9,2 @@
+`)
+	require.NoError(t, err)
+	hunks := diff.Files[0].Hunks
+	require.Len(t, hunks, 3)
+
+	assert.Empty(t, hunks[0].WholeRange.Lines)
+	assert.Equal(t, 1, hunks[0].Length())
+	assert.Equal(t, 4, hunks[1].Length())
+	assert.Empty(t, hunks[2].WholeRange.Lines)
+	assert.Equal(t, 1, hunks[2].Length())
+}
+
+func TestHunkLength(t *testing.T) {
+	diff := setup(t)
+
+	// The hunk for file1 is:
+	//
+	//	@@ -1,4 +1,4 @@
+	//	+add a line
+	//	 some
+	//	 lines
+	//	-in
+	//	 file1
+	hunk := diff.Files[0].Hunks[0]
+	assert.Equal(t, 5, hunk.LineCount())
+	assert.Equal(t, 6, hunk.Length())
+
+	// The "\ No newline at end of file" line is only in the Length.
+	hunk = diff.Files[2].Hunks[0]
+	assert.Equal(t, 4, hunk.LineCount())
+	assert.Equal(t, 6, hunk.Length())
+	assert.Equal(t, strings.Count(hunk.String(), "\n"), hunk.Length())
+}
+
+func TestHunkSection(t *testing.T) {
+	diff, err := Parse("diff --git a/file.c b/file.c\n" +
+		"index 504d2a1..50ccec3 100644\n" +
+		"--- a/file.c\n" +
+		"+++ b/file.c\n" +
+		"@@ -1 +1 @@ \t\tstatic int\tadd(int a,\tint b)\n" +
+		"-old\n" +
+		"+new\n" +
+		"@@ -5,1 +5,1 @@\n" +
+		"-old\n" +
+		"+new\n")
+	require.NoError(t, err)
+	hunks := diff.Files[0].Hunks
+	require.Len(t, hunks, 2)
+
+	assert.Equal(t, "\t\tstatic int\tadd(int a,\tint b)", hunks[0].HunkHeader)
+	assert.Equal(t, "static int\tadd(int a,\tint b)", hunks[0].Section())
+	assert.Equal(t, "", hunks[1].Section())
+}
+
+func TestLineSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, LineSimilarity("", ""))
+	assert.Equal(t, 1.0, LineSimilarity("same", "same"))
+	assert.Equal(t, 0.0, LineSimilarity("abc", "xyz"))
+	assert.Equal(t, 0.625, LineSimilarity("kitten", "sitten!!"))
+	// Lengths are in characters, not bytes.
+	assert.Equal(t, 0.6, LineSimilarity("héllo", "hél"))
+
+	// Long lines that only differ in a short part are compared exactly,
+	// and otherwise quickly.
+	long := strings.Repeat("a", 100000)
+	assert.Equal(t, 1-2.0/100000, LineSimilarity(long, long[:50000]+"bc"+long[50002:]))
+	assert.Equal(t, 0.0, LineSimilarity(strings.Repeat("ab", 50000), strings.Repeat("ba", 50000)))
+}
+
+func TestPairChanges(t *testing.T) {
+	diff, err := Parse(`--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@
+ func main() {
+-	fmt.Println("hello")
++	log.Println("bye")
++	fmt.Println("hello, world")
+ }
+-// end
++// the end
+`)
+	require.NoError(t, err)
+	hunk := diff.Files[0].Hunks[0]
+
+	pairs := hunk.PairChanges()
+	require.Len(t, pairs, 2)
+	assert.Equal(t, "\tfmt.Println(\"hello\")", pairs[0][0].Content)
+	assert.Equal(t, "\tfmt.Println(\"hello, world\")", pairs[0][1].Content)
+	assert.Equal(t, "// end", pairs[1][0].Content)
+	assert.Equal(t, "// the end", pairs[1][1].Content)
+
+	// On a tie, the first added line is paired, so the second removed line
+	// can be paired with the second added one.
+	diff, err = Parse(`--- a/file
++++ b/file
+@@ -1,2 +1,2 @@
+-x = 1
+-x = 2
++x = 3
++x = 2
+`)
+	require.NoError(t, err)
+	pairs = diff.Files[0].Hunks[0].PairChanges()
+	require.Len(t, pairs, 2)
+	assert.Equal(t, "x = 3", pairs[0][1].Content)
+	assert.Equal(t, "x = 2", pairs[1][1].Content)
+}
+
+func TestParseHunkHeader(t *testing.T) {
+	orig, updated, section, err := ParseHunkHeader("@@ -10,5 +12,6 @@ func main() {")
+	require.NoError(t, err)
+	assert.Equal(t, DiffRange{Start: 10, Length: 5}, orig)
+	assert.Equal(t, DiffRange{Start: 12, Length: 6}, updated)
+	assert.Equal(t, "func main() {", section)
+
+	orig, updated, section, err = ParseHunkHeader("@@ -10 +12 @@")
+	require.NoError(t, err)
+	assert.Equal(t, DiffRange{Start: 10, Length: 1}, orig)
+	assert.Equal(t, DiffRange{Start: 12, Length: 1}, updated)
+	assert.Equal(t, "", section)
+
+	orig, updated, _, err = ParseHunkHeader("@@ -0,0 +1,3 @@")
+	require.NoError(t, err)
+	assert.Equal(t, DiffRange{Start: 0, Length: 0}, orig)
+	assert.Equal(t, DiffRange{Start: 1, Length: 3}, updated)
+
+	orig, updated, _, err = ParseHunkHeader("@@ -7,2 +6,0 @@")
+	require.NoError(t, err)
+	assert.Equal(t, DiffRange{Start: 7, Length: 2}, orig)
+	assert.Equal(t, DiffRange{Start: 6, Length: 0}, updated)
+
+	_, _, _, err = ParseHunkHeader("@@ nonsense @@")
+	assert.EqualError(t, err, "Error parsing line: @@ nonsense @@")
+
+	// Hunks of one line are parsed the same way in a diff.
+	diff, err := Parse(`--- a/file
++++ b/file
+@@ -10 +10 @@
+-old
++new
+ after
+`)
+	require.NoError(t, err)
+	hunk := diff.Files[0].Hunks[0]
+	assert.Equal(t, 1, hunk.OrigRange.Length)
+	assert.Equal(t, 1, hunk.NewRange.Length)
+	assert.Len(t, hunk.WholeRange.Lines, 2)
+}
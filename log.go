@@ -0,0 +1,58 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// reLogCommit matches the line that starts each commit in "git log".
+var reLogCommit = regexp.MustCompile(`^commit [0-9a-f]{7,}\b`)
+
+// ParseLog parses the output of "git log -p", which has a commit header
+// followed by a diff for each of many commits, into a diff for each commit.
+func ParseLog(r io.Reader) ([]*Diff, error) {
+	return (&Parser{}).ParseLog(r)
+}
+
+// ParseLog is like the package-level ParseLog, but parses each diff with
+// the Parser's options.
+func (p *Parser) ParseLog(r io.Reader) ([]*Diff, error) {
+	var diffs []*Diff
+	var commit strings.Builder
+	parseCommit := func() error {
+		if commit.Len() == 0 {
+			return nil
+		}
+		diff, err := p.Parse(commit.String())
+		if err != nil {
+			return fmt.Errorf("commit %d: %w", len(diffs)+1, err)
+		}
+		diffs = append(diffs, diff)
+		commit.Reset()
+		return nil
+	}
+
+	err := p.readLines(r, func(line, token string) error {
+		// Lines in hunks start with a marker, and the lines of the commit
+		// message are indented, so this can only be the next commit.
+		if reLogCommit.MatchString(line) {
+			if err := parseCommit(); err != nil {
+				return err
+			}
+		}
+		commit.WriteString(token)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := parseCommit(); err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exampleLog = `commit 0123456789abcdef0123456789abcdef01234567
+Author: Jane Doe <jane@example.com>
+Date:   Mon Jan 2 15:04:05 2006 -0700
+
+    Add a greeting
+
+diff --git a/hello.txt b/hello.txt
+new file mode 100644
+index 0000000..ce01362
+--- /dev/null
++++ b/hello.txt
+@@ -0,0 +1 @@
++hello
+commit 89abcdef0123456789abcdef0123456789abcdef
+Author: John Doe <john@example.com>
+Date:   Tue Jan 3 15:04:05 2006 -0700
+
+    Change the greeting
+    
+    commit 0000000 isn't the start of a commit when it's indented.
+
+diff --git a/hello.txt b/hello.txt
+index ce01362..cc628cc 100644
+--- a/hello.txt
++++ b/hello.txt
+@@ -1 +1 @@
+-hello
++goodbye
+`
+
+func TestParseLog(t *testing.T) {
+	diffs, err := ParseLog(strings.NewReader(exampleLog))
+	require.NoError(t, err)
+	require.Len(t, diffs, 2)
+
+	first, second := diffs[0], diffs[1]
+	assert.Equal(t, "0123456789abcdef0123456789abcdef01234567", first.Commit)
+	assert.Equal(t, "Jane Doe <jane@example.com>", first.Author)
+	assert.Equal(t, "Add a greeting", first.Message)
+	require.Len(t, first.Files, 1)
+	assert.Equal(t, NEW, first.Files[0].Mode)
+	assert.Equal(t, "hello", first.Files[0].Hunks[0].NewRange.Lines[0].Content)
+
+	assert.Equal(t, "89abcdef0123456789abcdef0123456789abcdef", second.Commit)
+	assert.Equal(t, "John Doe <john@example.com>", second.Author)
+	assert.Equal(t, "Change the greeting\n\ncommit 0000000 isn't the start of a commit when it's indented.", second.Message)
+	require.Len(t, second.Files, 1)
+	assert.Equal(t, MODIFIED, second.Files[0].Mode)
+	assert.Equal(t, "89abcdef0123456789abcdef0123456789abcdef", second.Files[0].Commit)
+
+	assert.Equal(t, exampleLog, first.Raw+second.Raw)
+}
+
+func TestParseLogMaxLineBytes(t *testing.T) {
+	parser := Parser{MaxLineBytes: 40}
+	_, err := parser.ParseLog(strings.NewReader(exampleLog))
+	require.EqualError(t, err, "line 1 is longer than the maximum of 40 bytes")
+}
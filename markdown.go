@@ -0,0 +1,70 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var reFenceOpen = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^ \t`]*)")
+
+// ExtractFromMarkdown returns the contents of the fenced code blocks in md
+// that contain diffs, ready to be passed to Parse. These are the blocks
+// tagged with "diff" or "patch", and untagged blocks that look like diffs.
+func ExtractFromMarkdown(md string) []string {
+	var diffs []string
+
+	lines := strings.Split(md, "\n")
+	for i := 0; i < len(lines); i++ {
+		m := reFenceOpen.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		indent, fence, lang := len(m[1]), m[2], strings.ToLower(m[3])
+
+		// Collect the block, up to a closing fence at least as long as the
+		// opening one, or the end of the document.
+		var block []string
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if trimmed := strings.TrimLeft(l, " "); len(l)-len(trimmed) <= 3 &&
+				strings.HasPrefix(trimmed, fence) &&
+				strings.Trim(trimmed, fence[:1]+" \t") == "" {
+				break
+			}
+			// Remove the fence's indentation from the contents.
+			for j := 0; j < indent && strings.HasPrefix(l, " "); j++ {
+				l = l[1:]
+			}
+			block = append(block, l)
+		}
+
+		switch lang {
+		case "diff", "patch":
+		case "":
+			if !looksLikeDiff(block) {
+				continue
+			}
+		default:
+			continue
+		}
+		diffs = append(diffs, strings.Join(block, "\n")+"\n")
+	}
+
+	return diffs
+}
+
+// looksLikeDiff returns whether lines have the headers of a diff.
+func looksLikeDiff(lines []string) bool {
+	for i, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "diff "), strings.HasPrefix(l, "@@ "):
+			return true
+		case strings.HasPrefix(l, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			return true
+		}
+	}
+	return false
+}
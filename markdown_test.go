@@ -0,0 +1,59 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractFromMarkdown(t *testing.T) {
+	md := "Here's the fix:\n" +
+		"\n" +
+		"```diff\n" +
+		"diff --git a/file1 b/file1\n" +
+		"index 504d2a1..50ccec3 100644\n" +
+		"--- a/file1\n" +
+		"+++ b/file1\n" +
+		"@@ -1 +1 @@\n" +
+		"-old\n" +
+		"+new\n" +
+		"```\n" +
+		"\n" +
+		"And some code, which isn't a diff:\n" +
+		"\n" +
+		"```go\n" +
+		"func main() {}\n" +
+		"```\n" +
+		"\n" +
+		"  ~~~~\n" +
+		"  --- a/file2\n" +
+		"  +++ b/file2\n" +
+		"  @@ -1 +1 @@\n" +
+		"  -two\n" +
+		"  +TWO\n" +
+		"  ~~~~\n" +
+		"\n" +
+		"```\n" +
+		"just some text\n" +
+		"```\n"
+
+	diffs := ExtractFromMarkdown(md)
+	require.Len(t, diffs, 2)
+	assert.Equal(t, "diff --git a/file1 b/file1\n"+
+		"index 504d2a1..50ccec3 100644\n"+
+		"--- a/file1\n"+
+		"+++ b/file1\n"+
+		"@@ -1 +1 @@\n"+
+		"-old\n"+
+		"+new\n", diffs[0])
+
+	diff, err := Parse(diffs[1])
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, "file2", diff.Files[0].NewName)
+	assert.Equal(t, map[string][]int{"file2": {1}}, diff.Changed())
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import "fmt"
+
+// Move is a block of lines removed from one file and added, unchanged, to
+// another.
+type Move struct {
+	// OrigName and OrigStart are the file the lines were removed from, and
+	// the number of the first of them in its original version.
+	OrigName  string
+	OrigStart int
+	// NewName and NewStart are the file the lines were added to, and the
+	// number of the first of them in its new version.
+	NewName  string
+	NewStart int
+
+	// Lines is the content of the lines that moved.
+	Lines []string
+}
+
+// DetectMoves finds blocks of at least minLines lines that were removed from
+// one file and added to another, such as when code is moved while
+// refactoring. Lines only match if their content is exactly the same, and
+// blocks can't span hunks.
+func (d *Diff) DetectMoves(minLines int) []Move {
+	if minLines < 1 {
+		minLines = 1
+	}
+
+	var moves []Move
+	for _, from := range d.Files {
+		for _, removed := range lineRuns(from, REMOVED) {
+			for _, to := range d.Files {
+				if to == from {
+					continue
+				}
+				for _, added := range lineRuns(to, ADDED) {
+					for _, m := range matchingBlocks(removed, added, minLines) {
+						moves = append(moves, Move{
+							OrigName:  from.OrigName,
+							OrigStart: removed[m.i].Number,
+							NewName:   to.NewName,
+							NewStart:  added[m.j].Number,
+							Lines:     lineContents(removed[m.i : m.i+m.n]),
+						})
+					}
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// lineRuns returns the runs of consecutive lines with the mode in the file.
+func lineRuns(f *DiffFile, mode DiffLineMode) [][]*DiffLine {
+	var runs [][]*DiffLine
+	for _, h := range f.Hunks {
+		var run []*DiffLine
+		for _, l := range h.WholeRange.Lines {
+			if l.Mode == mode {
+				run = append(run, l)
+				continue
+			}
+			if len(run) > 0 {
+				runs = append(runs, run)
+				run = nil
+			}
+		}
+		if len(run) > 0 {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+type block struct {
+	i, j, n int
+}
+
+// matchingBlocks returns the longest runs of at least minLines lines with
+// the same content in a and b, starting at a[i] and b[j].
+func matchingBlocks(a, b []*DiffLine, minLines int) []block {
+	var blocks []block
+	for i := range a {
+		for j := range b {
+			// Only start from the beginning of each match.
+			if i > 0 && j > 0 && a[i-1].Content == b[j-1].Content {
+				continue
+			}
+			n := 0
+			for i+n < len(a) && j+n < len(b) && a[i+n].Content == b[j+n].Content {
+				n++
+			}
+			if n >= minLines {
+				blocks = append(blocks, block{i: i, j: j, n: n})
+			}
+		}
+	}
+	return blocks
+}
+
+func lineContents(lines []*DiffLine) []string {
+	contents := make([]string, 0, len(lines))
+	for _, l := range lines {
+		contents = append(contents, l.Content)
+	}
+	return contents
+}
+
+// LinkSplitRenames finds files that were deleted and added again under
+// another name, and replaces each pair with a single RENAMED file, like
+// git's own rename detection. Files are paired if the fraction of their
+// lines that are the same, from 0 to 1, is at least threshold. Each deleted
+// file is paired with the most similar added file, and the hunks of the
+// renamed file remove all of its original lines and add all of its new ones.
+func (d *Diff) LinkSplitRenames(threshold float64) {
+	paired := make(map[*DiffFile]bool)
+	for _, from := range d.Files {
+		if from.Mode != DELETED {
+			continue
+		}
+
+		var best *DiffFile
+		bestScore := 0.0
+		for _, to := range d.Files {
+			if to.Mode != NEW || paired[to] {
+				continue
+			}
+			if score := fileSimilarity(from, to); score >= threshold && score > bestScore {
+				best, bestScore = to, score
+			}
+		}
+		if best == nil {
+			continue
+		}
+
+		paired[from], paired[best] = true, true
+		best.Mode = RENAMED
+		best.OrigName = from.OrigName
+		best.OldMode = from.OldMode
+		best.OrigHash = from.OrigHash
+		if fileMode(best.OldMode) == fileMode(best.NewMode) {
+			best.IndexMode = fileMode(best.NewMode)
+		}
+		best.Similarity = int(bestScore * 100)
+		best.DiffHeader = fmt.Sprintf("diff --git a/%s b/%s", best.OrigName, best.NewName)
+		best.Hunks = []*DiffHunk{replacementHunk(from, best)}
+		best.renumberPositions()
+	}
+
+	files := d.Files[:0]
+	for _, f := range d.Files {
+		if f.Mode != DELETED || !paired[f] {
+			files = append(files, f)
+		}
+	}
+	d.Files = files
+}
+
+// fileSimilarity returns the fraction of the lines removed from the deleted
+// file that were added to the new one, out of the lines in the larger of
+// the two, or zero if both are empty.
+func fileSimilarity(deleted, added *DiffFile) float64 {
+	counts := make(map[string]int)
+	removed := 0
+	for _, l := range deleted.OrigLines() {
+		counts[l.Content]++
+		removed++
+	}
+
+	common, total := 0, 0
+	for _, l := range added.NewLines() {
+		total++
+		if counts[l.Content] > 0 {
+			counts[l.Content]--
+			common++
+		}
+	}
+	if removed > total {
+		total = removed
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(common) / float64(total)
+}
+
+// replacementHunk returns a hunk that removes the lines of the deleted file
+// and adds the lines of the new one.
+func replacementHunk(deleted, added *DiffFile) *DiffHunk {
+	hunk := &DiffHunk{
+		OrigRange: DiffRange{Lines: deleted.OrigLines()},
+		NewRange:  DiffRange{Lines: added.NewLines()},
+	}
+	if len(deleted.Hunks) > 0 {
+		hunk.OrigRange.Start = deleted.Hunks[0].OrigRange.Start
+	}
+	if len(added.Hunks) > 0 {
+		hunk.NewRange.Start = added.Hunks[0].NewRange.Start
+	}
+	hunk.OrigRange.Length = len(hunk.OrigRange.Lines)
+	hunk.NewRange.Length = len(hunk.NewRange.Lines)
+
+	hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, hunk.OrigRange.Lines...)
+	hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, hunk.NewRange.Lines...)
+	return hunk
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectMoves(t *testing.T) {
+	diff, err := Parse(`diff --git a/server.go b/server.go
+--- a/server.go
++++ b/server.go
+@@ -10,7 +10,2 @@ func serve() {
+ 	listen()
+-}
+-
+-func helper(x int) int {
+-	y := x * 2
+-	return y + 1
+ }
+diff --git a/util.go b/util.go
+--- a/util.go
++++ b/util.go
+@@ -1,2 +1,8 @@
+ package main
++
++func helper(x int) int {
++	y := x * 2
++	return y + 1
++}
++
+ // more
+`)
+	require.NoError(t, err)
+
+	moves := diff.DetectMoves(3)
+	require.Len(t, moves, 1)
+	assert.Equal(t, Move{
+		OrigName:  "server.go",
+		OrigStart: 12,
+		NewName:   "util.go",
+		NewStart:  2,
+		Lines: []string{
+			"",
+			"func helper(x int) int {",
+			"\ty := x * 2",
+			"\treturn y + 1",
+		},
+	}, moves[0])
+
+	assert.Empty(t, diff.DetectMoves(5))
+
+	// A deleted file added again under another name is a move too.
+	assert.Equal(t, []Move{{
+		OrigName:  "file2",
+		OrigStart: 1,
+		NewName:   "newname",
+		NewStart:  1,
+		Lines:     []string{"other", "lines", "in", "file2"},
+	}}, setup(t).DetectMoves(2))
+}
+
+func TestLinkSplitRenames(t *testing.T) {
+	diff, err := Parse(`diff --git a/old.go b/old.go
+deleted file mode 100644
+index 1111111..0000000
+--- a/old.go
++++ /dev/null
+@@ -1,4 +0,0 @@
+-package main
+-
+-func main() {
+-}
+diff --git a/other.txt b/other.txt
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/other.txt
+@@ -0,0 +1 @@
++unrelated
+diff --git a/new.go b/new.go
+new file mode 100644
+index 0000000..2222222
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,5 @@
++package main
++
++func main() {
++	run()
++}
+`)
+	require.NoError(t, err)
+
+	diff.LinkSplitRenames(0.9)
+	require.Len(t, diff.Files, 3)
+
+	diff.LinkSplitRenames(0.5)
+	require.Len(t, diff.Files, 2)
+	assert.Equal(t, "other.txt", diff.Files[0].NewName)
+	assert.Equal(t, NEW, diff.Files[0].Mode)
+
+	f := diff.Files[1]
+	assert.Equal(t, RENAMED, f.Mode)
+	assert.Equal(t, "old.go", f.OrigName)
+	assert.Equal(t, "new.go", f.NewName)
+	assert.Equal(t, 80, f.Similarity)
+	require.NoError(t, f.CheckPositions())
+	assert.Equal(t, `diff --git a/old.go b/new.go
+similarity index 80%
+rename from old.go
+rename to new.go
+index 1111111..2222222 100644
+--- a/old.go
++++ b/new.go
+@@ -1,4 +1,5 @@
+-package main
+-
+-func main() {
+-}
++package main
++
++func main() {
++	run()
++}
+`, f.String())
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseRaw parses the output of "git diff --raw", which lists the files that
+// changed, with their modes, blob IDs and status, but not what changed in
+// them. The files in the returned diff have no hunks.
+func ParseRaw(s string) (*Diff, error) {
+	diff := &Diff{Raw: s}
+	for i, l := range strings.Split(s, "\n") {
+		l = strings.TrimSuffix(l, "\r")
+		if l == "" {
+			continue
+		}
+		file, err := parseRawLine(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		diff.Files = append(diff.Files, file)
+	}
+	return diff, nil
+}
+
+// parseRawLine parses a single line of "git diff --raw" output, such as
+// ":100644 100644 bcd1234 0123456 M\tfile0".
+func parseRawLine(l string) (*DiffFile, error) {
+	if !strings.HasPrefix(l, ":") {
+		return nil, fmt.Errorf("not a raw diff line: %q", l)
+	}
+	info, paths, ok := strings.Cut(l[1:], "\t")
+	fields := strings.Fields(info)
+	if !ok || len(fields) != 5 {
+		return nil, fmt.Errorf("not a raw diff line: %q", l)
+	}
+
+	file := &DiffFile{
+		OldMode:  fields[0],
+		NewMode:  fields[1],
+		OrigHash: fields[2],
+		NewHash:  fields[3],
+	}
+
+	// The status may be followed by a score, which for renames and copies
+	// is how similar the files are.
+	status := fields[4]
+	letter := status[:1]
+	if letter == "T" {
+		// A change in the type of the file, such as to a symlink.
+		letter = "M"
+	}
+	mode, err := FileModeFromStatus(letter)
+	if err != nil {
+		return nil, err
+	}
+	file.Mode = mode
+	if len(status) > 1 {
+		score, err := strconv.Atoi(status[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid score in status %q", status)
+		}
+		if file.Mode == RENAMED || file.Mode == COPIED {
+			file.Similarity = score
+		}
+	}
+
+	// Renames and copies have both names, other changes have one.
+	from, to, ok := strings.Cut(paths, "\t")
+	if !ok {
+		to = from
+	}
+	file.OrigName, file.NewName = from, to
+	return file, nil
+}
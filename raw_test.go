@@ -0,0 +1,47 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRaw(t *testing.T) {
+	diff, err := ParseRaw(`:100644 100644 bcd1234 0123456 M	file0
+:000000 100644 0000000 1234567 A	new.txt
+:100644 000000 abcdef0 0000000 D	old.txt
+:100644 100755 fedcba9 fedcba9 M	script.sh
+:100644 100644 abcdef0 9876543 R086	src/before.go	src/after.go
+:100644 100644 1111111 1111111 C100	template.txt	copy.txt
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 6)
+
+	for i, expected := range []DiffFile{
+		{Mode: MODIFIED, OrigName: "file0", NewName: "file0", OldMode: "100644", NewMode: "100644", OrigHash: "bcd1234", NewHash: "0123456"},
+		{Mode: NEW, OrigName: "new.txt", NewName: "new.txt", OldMode: "000000", NewMode: "100644", OrigHash: "0000000", NewHash: "1234567"},
+		{Mode: DELETED, OrigName: "old.txt", NewName: "old.txt", OldMode: "100644", NewMode: "000000", OrigHash: "abcdef0", NewHash: "0000000"},
+		{Mode: MODIFIED, OrigName: "script.sh", NewName: "script.sh", OldMode: "100644", NewMode: "100755", OrigHash: "fedcba9", NewHash: "fedcba9"},
+		{Mode: RENAMED, OrigName: "src/before.go", NewName: "src/after.go", OldMode: "100644", NewMode: "100644", OrigHash: "abcdef0", NewHash: "9876543", Similarity: 86},
+		{Mode: COPIED, OrigName: "template.txt", NewName: "copy.txt", OldMode: "100644", NewMode: "100644", OrigHash: "1111111", NewHash: "1111111", Similarity: 100},
+	} {
+		expected := expected
+		assert.Equal(t, &expected, diff.Files[i])
+	}
+}
+
+func TestParseRawErrors(t *testing.T) {
+	for _, raw := range []string{
+		"diff --git a/file b/file",
+		":100644 100644 bcd1234 0123456 M",
+		":100644 100644 bcd1234 0123456 Q\tfile",
+		":100644 100644 bcd1234 0123456 Rxx\told\tnew",
+	} {
+		_, err := ParseRaw(raw)
+		assert.Error(t, err, raw)
+	}
+}
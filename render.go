@@ -0,0 +1,284 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderOptions controls how a diff is rendered by Diff.StringWithOptions.
+type RenderOptions struct {
+	// TrailingNewline ends the diff with a newline after its last line,
+	// like git does.
+	TrailingNewline bool
+}
+
+// String renders the diff in the format of "git diff", with each of its
+// files rendered by DiffFile.String.
+func (d *Diff) String() string {
+	return d.StringWithOptions(RenderOptions{TrailingNewline: true})
+}
+
+// StringWithOptions is like String, but rendered according to opts.
+func (d *Diff) StringWithOptions(opts RenderOptions) string {
+	var sb strings.Builder
+	for _, f := range d.Files {
+		sb.WriteString(f.String())
+	}
+	s := sb.String()
+	if !opts.TrailingNewline {
+		s = strings.TrimSuffix(s, "\n")
+	}
+	return s
+}
+
+// String renders the file in the format of "git diff", from its parsed
+// fields. The header is rebuilt rather than copied from DiffHeader, so it
+// only has the lines git would write for those fields, in git's order.
+// The contents of binary files aren't kept, so they're always rendered as
+// "Binary files ... differ".
+func (f *DiffFile) String() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "diff --git a/%s b/%s\n", f.OrigName, f.NewName)
+	switch {
+	case f.Mode == NEW:
+		fmt.Fprintf(&sb, "new file mode %s\n", fileMode(f.NewMode))
+	case f.Mode == DELETED:
+		fmt.Fprintf(&sb, "deleted file mode %s\n", fileMode(f.OldMode))
+	case f.OldMode != "" && f.NewMode != "" && f.OldMode != f.NewMode:
+		fmt.Fprintf(&sb, "old mode %s\nnew mode %s\n", f.OldMode, f.NewMode)
+	}
+	if f.Mode == RENAMED || f.Mode == COPIED {
+		if f.Similarity > 0 {
+			fmt.Fprintf(&sb, "similarity index %d%%\n", f.Similarity)
+		}
+		verb := "rename"
+		if f.Mode == COPIED {
+			verb = "copy"
+		}
+		fmt.Fprintf(&sb, "%s from %s\n%s to %s\n", verb, f.OrigName, verb, f.NewName)
+	}
+	if f.OrigHash != "" || f.NewHash != "" {
+		fmt.Fprintf(&sb, "index %s..%s", f.OrigHash, f.NewHash)
+		if f.IndexMode != "" {
+			sb.WriteString(" " + f.IndexMode)
+		}
+		sb.WriteString("\n")
+	}
+
+	from, to := "a/"+f.OrigName, "b/"+f.NewName
+	switch f.Mode {
+	case NEW:
+		from = "/dev/null"
+	case DELETED:
+		to = "/dev/null"
+	}
+	if f.Binary {
+		fmt.Fprintf(&sb, "Binary files %s and %s differ\n", from, to)
+		return sb.String()
+	}
+	if len(f.Hunks) == 0 {
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", from, to)
+	for _, h := range f.Hunks {
+		sb.WriteString(h.String())
+	}
+	return sb.String()
+}
+
+// fileMode returns mode, or the mode of a regular file if it isn't known.
+func fileMode(mode string) string {
+	if mode == "" {
+		return "100644"
+	}
+	return mode
+}
+
+// GitHubPatch renders the file's hunks in the same format as the "patch"
+// field of a file in the GitHub API, which is like String, but without the
+// header before the first "@@" line or a newline at the end.
+func (f *DiffFile) GitHubPatch() string {
+	var sb strings.Builder
+	for _, h := range f.Hunks {
+		sb.WriteString(h.String())
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// String renders the hunk in the format of "git diff", starting with its
+// "@@" header.
+func (hunk *DiffHunk) String() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "@@ -%s +%s @@", hunk.OrigRange.String(), hunk.NewRange.String())
+	if hunk.HunkHeader != "" {
+		sb.WriteString(" " + hunk.HunkHeader)
+	}
+	sb.WriteString("\n")
+
+	for _, l := range hunk.WholeRange.Lines {
+		switch l.Mode {
+		case ADDED:
+			sb.WriteString("+")
+		case REMOVED:
+			sb.WriteString("-")
+		default:
+			sb.WriteString(" ")
+		}
+		sb.WriteString(l.Content + "\n")
+		if l.NoNewline {
+			sb.WriteString("\\ No newline at end of file\n")
+		}
+	}
+	return sb.String()
+}
+
+// SplitByFile returns a diff for each file in the diff, with the same commit
+// details. The Raw of each is the file's own part of the diff's Raw, or the
+// file rendered by DiffFile.String if it wasn't parsed from Raw or its part
+// isn't its own, such as when it was merged from several parts with
+// Parser.AllowInterleaved. Each diff has a copy of the file and its hunks,
+// with RawStart and RawEnd relative to its own Raw.
+func (d *Diff) SplitByFile() []*Diff {
+	diffs := make([]*Diff, 0, len(d.Files))
+	for _, f := range d.Files {
+		file := *f
+		file.RawStart, file.RawEnd = 0, 0
+		parsed := f.RawEnd > f.RawStart && f.RawEnd <= len(d.Raw) && !d.sharesRaw(f)
+		raw := f.String()
+		if parsed {
+			raw = d.Raw[f.RawStart:f.RawEnd]
+			file.RawEnd = len(raw)
+		}
+
+		file.Hunks = nil
+		for _, h := range f.Hunks {
+			hunk := *h
+			hunk.RawStart, hunk.RawEnd = 0, 0
+			if parsed {
+				hunk.RawStart, hunk.RawEnd = h.RawStart-f.RawStart, h.RawEnd-f.RawStart
+			}
+			file.Hunks = append(file.Hunks, &hunk)
+		}
+		diffs = append(diffs, &Diff{
+			Files:   []*DiffFile{&file},
+			Raw:     raw,
+			PullID:  d.PullID,
+			Commit:  d.Commit,
+			Author:  d.Author,
+			Message: d.Message,
+		})
+	}
+	return diffs
+}
+
+// sharesRaw returns whether another file starts inside f's part of the
+// diff's Raw.
+func (d *Diff) sharesRaw(f *DiffFile) bool {
+	for _, other := range d.Files {
+		if other != f && other.RawEnd > other.RawStart && other.RawStart >= f.RawStart && other.RawStart < f.RawEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// collapsedContent is the Content of the line that stands in for the
+// unchanged lines hidden by Collapse.
+const collapsedContent = "..."
+
+// Collapse returns a copy of the diff for display, with long runs of
+// unchanged lines between the changes in its hunks replaced by a single
+// unchanged line with the content "...", like GitHub's collapsed view. The
+// replacement line has no numbers or position, and its Collapsed is the
+// number of lines it hides. Only maxContext lines are kept next to the
+// changes on either side, and a run is only collapsed if that hides more
+// than one line. The unchanged lines at the start and end of each hunk are
+// all kept. The ranges of the hunks and the numbers and positions of the
+// lines are kept, while the hidden lines are also left out of OrigRange and
+// NewRange, without a replacement.
+func (d *Diff) Collapse(maxContext int) *Diff {
+	if maxContext < 0 {
+		maxContext = 0
+	}
+
+	out := *d
+	out.Files = make([]*DiffFile, 0, len(d.Files))
+	for _, f := range d.Files {
+		file := *f
+		file.Hunks = nil
+		for _, h := range f.Hunks {
+			file.Hunks = append(file.Hunks, h.collapse(maxContext))
+		}
+		out.Files = append(out.Files, &file)
+	}
+	return &out
+}
+
+// collapse returns a copy of the hunk with long runs of unchanged lines
+// collapsed, as described by Diff.Collapse.
+func (hunk *DiffHunk) collapse(maxContext int) *DiffHunk {
+	lines := hunk.WholeRange.Lines
+	c := *hunk
+	c.WholeRange.Lines = nil
+
+	// hidden holds the ordinals of the hidden unchanged lines, to find
+	// their copies in OrigRange.
+	hidden := make(map[int]bool)
+	unchanged := 0
+	for i := 0; i < len(lines); {
+		if lines[i].Mode != UNCHANGED {
+			c.WholeRange.Lines = append(c.WholeRange.Lines, lines[i])
+			i++
+			continue
+		}
+		start := i
+		for i < len(lines) && lines[i].Mode == UNCHANGED {
+			i++
+		}
+
+		// Only runs between changes are collapsed, keeping the lines next
+		// to the changes on either side.
+		interior := start > 0 && i < len(lines)
+		if !interior || i-start-2*maxContext < 2 {
+			c.WholeRange.Lines = append(c.WholeRange.Lines, lines[start:i]...)
+			unchanged += i - start
+			continue
+		}
+
+		hide := i - start - 2*maxContext
+		c.WholeRange.Lines = append(c.WholeRange.Lines, lines[start:start+maxContext]...)
+		c.WholeRange.Lines = append(c.WholeRange.Lines, &DiffLine{Mode: UNCHANGED, Content: collapsedContent, Collapsed: hide})
+		c.WholeRange.Lines = append(c.WholeRange.Lines, lines[i-maxContext:i]...)
+		for j := maxContext; j < maxContext+hide; j++ {
+			hidden[unchanged+j] = true
+		}
+		unchanged += i - start
+	}
+
+	c.OrigRange.Lines = withoutHidden(hunk.OrigRange.Lines, hidden)
+	c.NewRange.Lines = withoutHidden(hunk.NewRange.Lines, hidden)
+	return &c
+}
+
+// withoutHidden returns the lines, without the unchanged lines whose
+// ordinals among the unchanged lines are hidden.
+func withoutHidden(lines []*DiffLine, hidden map[int]bool) []*DiffLine {
+	var kept []*DiffLine
+	unchanged := 0
+	for _, l := range lines {
+		if l.Mode == UNCHANGED {
+			unchanged++
+			if hidden[unchanged-1] {
+				continue
+			}
+		}
+		kept = append(kept, l)
+	}
+	return kept
+}
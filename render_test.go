@@ -0,0 +1,277 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderRename(t *testing.T) {
+	raw := `diff --git a/lib/old.go b/lib/new.go
+similarity index 87%
+rename from lib/old.go
+rename to lib/new.go
+index 3b18e51..8b13789 100644
+--- a/lib/old.go
++++ b/lib/new.go
+@@ -1,4 +1,4 @@ package lib
+ 
+-func Old() {}
++func New() {}
+ 
+ var x = 1
+`
+	diff, err := Parse(raw)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+
+	file := diff.Files[0]
+	assert.Equal(t, RENAMED, file.Mode)
+	assert.Equal(t, 87, file.Similarity)
+	assert.Equal(t, "100644", file.IndexMode)
+	assert.Equal(t, raw, file.String())
+	assert.Equal(t, raw, diff.String())
+}
+
+func TestRenderFiles(t *testing.T) {
+	raw := `diff --git a/script.sh b/script.sh
+old mode 100644
+new mode 100755
+diff --git a/hello.txt b/hello.txt
+index 0d1e2f3..4a5b6c7 100644
+--- a/hello.txt
++++ b/hello.txt
+@@ -1,2 +1,2 @@
+ hello
+-world
+\ No newline at end of file
++there
+\ No newline at end of file
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/image.png b/image.png
+index 1a2b3c4..5d6e7f8 100644
+Binary files a/image.png and b/image.png differ
+`
+	diff, err := Parse(raw)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 4)
+	assert.Equal(t, raw, diff.String())
+}
+
+func TestRenderTrailingNewline(t *testing.T) {
+	raw := `diff --git a/hello.txt b/hello.txt
+--- a/hello.txt
++++ b/hello.txt
+@@ -1 +1 @@
+-hello
++goodbye
+`
+	diff, err := Parse(raw)
+	require.NoError(t, err)
+
+	assert.Equal(t, raw, diff.StringWithOptions(RenderOptions{TrailingNewline: true}))
+	assert.Equal(t, strings.TrimSuffix(raw, "\n"), diff.StringWithOptions(RenderOptions{}))
+	assert.Equal(t, diff.String(), diff.StringWithOptions(RenderOptions{TrailingNewline: true}))
+
+	assert.Equal(t, "", (&Diff{}).StringWithOptions(RenderOptions{}))
+}
+
+func TestSplitByFile(t *testing.T) {
+	diff := setup(t)
+	diffs := diff.SplitByFile()
+	require.Len(t, diffs, len(diff.Files))
+
+	for i, d := range diffs {
+		f := diff.Files[i]
+		require.Len(t, d.Files, 1)
+		assert.Equal(t, diff.Raw[f.RawStart:f.RawEnd], d.Raw)
+		assert.Equal(t, d.Raw, d.Raw[d.Files[0].RawStart:d.Files[0].RawEnd])
+
+		reparsed, err := Parse(d.Raw)
+		require.NoError(t, err)
+		require.Len(t, reparsed.Files, 1, d.Raw)
+		file := reparsed.Files[0]
+		assert.Equal(t, f.Mode, file.Mode, d.Raw)
+		assert.Equal(t, f.OrigName, file.OrigName)
+		assert.Equal(t, f.NewName, file.NewName)
+		assert.Equal(t, file.Hunks, d.Files[0].Hunks)
+	}
+
+	// The files in the original diff are unchanged.
+	assert.Equal(t, setup(t).Files, diff.Files)
+}
+
+func TestSplitByFileKeepsRaw(t *testing.T) {
+	diff, err := Parse(`diff --git a/file b/file
+--- a/file	2020-01-01 12:00:00.000000000 +0000
++++ b/file	2020-01-02 12:00:00.000000000 +0000
+@@ -1 +1 @@
+-a
++b
+diff --git a/image.png b/image.png
+index 1111111..2222222 100644
+GIT binary patch
+literal 5
+McmZQzWMXCk00V~
+
+literal 0
+HcmV?d00001
+
+`)
+	require.NoError(t, err)
+
+	diffs := diff.SplitByFile()
+	require.Len(t, diffs, 2)
+	assert.Equal(t, "--- a/file\t2020-01-01 12:00:00.000000000 +0000", strings.Split(diffs[0].Raw, "\n")[1])
+	assert.Contains(t, diffs[1].Raw, "GIT binary patch\nliteral 5\n")
+	assert.True(t, strings.HasPrefix(diffs[1].Raw, "diff --git a/image.png b/image.png\n"))
+
+	hunk := diffs[0].Files[0].Hunks[0]
+	assert.Equal(t, "@@ -1 +1 @@\n-a\n+b\n", diffs[0].Raw[hunk.RawStart:hunk.RawEnd])
+}
+
+func TestSplitByFileInterleaved(t *testing.T) {
+	parser := Parser{AllowInterleaved: true}
+	diff, err := parser.Parse(`diff --git a/f b/f
+--- a/f
++++ b/f
+@@ -1 +1 @@
+-one
++ONE
+diff --git a/g b/g
+--- a/g
++++ b/g
+@@ -1 +1 @@
+-gee
++GEE
+diff --git a/f b/f
+--- a/f
++++ b/f
+@@ -10 +10 @@
+-ten
++TEN
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	diffs := diff.SplitByFile()
+	require.Len(t, diffs, 2)
+	assert.Equal(t, diff.Files[0].String(), diffs[0].Raw)
+	assert.NotContains(t, diffs[0].Raw, "gee")
+	assert.Contains(t, diffs[0].Raw, "+TEN\n")
+	assert.Equal(t, "diff --git a/g b/g\n--- a/g\n+++ b/g\n@@ -1 +1 @@\n-gee\n+GEE\n", diffs[1].Raw)
+}
+
+func TestRenderIndexWithoutMode(t *testing.T) {
+	raw := `diff --git a/file b/file
+index 3b18e51..8b13789
+--- a/file
++++ b/file
+@@ -1 +1 @@
+-old
++new
+`
+	diff, err := Parse(raw)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+
+	file := diff.Files[0]
+	assert.Equal(t, "3b18e51", file.OrigHash)
+	assert.Equal(t, "8b13789", file.NewHash)
+	assert.Equal(t, "", file.IndexMode)
+	assert.Equal(t, raw, diff.String())
+}
+
+func TestGitHubPatch(t *testing.T) {
+	diff, err := Parse(`diff --git a/README.md b/README.md
+index 5d6c2b1..8e1f3a4 100644
+--- a/README.md
++++ b/README.md
+@@ -1,3 +1,3 @@
+ # Project
+-Old description.
++New description.
+ 
+@@ -10,2 +10,3 @@ ## Usage
+ Run it.
+-The end.
+\ No newline at end of file
++The end.
++More.
+\ No newline at end of file
+`)
+	require.NoError(t, err)
+
+	// The "patch" field of the file from the GitHub API.
+	patch := "@@ -1,3 +1,3 @@\n # Project\n-Old description.\n+New description.\n \n@@ -10,2 +10,3 @@ ## Usage\n Run it.\n-The end.\n\\ No newline at end of file\n+The end.\n+More.\n\\ No newline at end of file"
+	assert.Equal(t, patch, diff.Files[0].GitHubPatch())
+}
+
+func TestCollapse(t *testing.T) {
+	diff, err := Parse(`--- a/file
++++ b/file
+@@ -1,14 +1,14 @@
+ 1
+ 2
+ 3
+-4
++four
+ 5
+ 6
+ 7
+ 8
+ 9
+ 10
+-11
++eleven
+ 12
+ 13
+ 14
+`)
+	require.NoError(t, err)
+
+	collapsed := diff.Collapse(1)
+	hunk := collapsed.Files[0].Hunks[0]
+	var contents []string
+	for _, l := range hunk.WholeRange.Lines {
+		contents = append(contents, l.Content)
+	}
+	assert.Equal(t, []string{"1", "2", "3", "4", "four", "5", "...", "10", "11", "eleven", "12", "13", "14"}, contents)
+
+	stand := hunk.WholeRange.Lines[6]
+	assert.Equal(t, 4, stand.Collapsed)
+	assert.Equal(t, 0, stand.Number)
+	assert.Equal(t, 0, stand.Position)
+	assert.Equal(t, 10, hunk.WholeRange.Lines[7].Number)
+	assert.Equal(t, 11, hunk.WholeRange.Lines[7].Position)
+	assert.Equal(t, diff.Files[0].Hunks[0].NewRange.Length, hunk.NewRange.Length)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 10, 11, 12, 13, 14}, numbers(hunk.NewRange.Lines))
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 10, 11, 12, 13, 14}, numbers(hunk.OrigRange.Lines))
+	assert.Contains(t, collapsed.HTML(), `<tr class="collapsed"><td colspan="3">4 hidden lines</td></tr>`)
+
+	assert.Equal(t, 2, diff.Collapse(2).Files[0].Hunks[0].WholeRange.Lines[7].Collapsed)
+
+	// Runs that wouldn't hide more than one line are kept.
+	assert.Len(t, diff.Collapse(3).Files[0].Hunks[0].WholeRange.Lines, 16)
+
+	// The original diff is unchanged.
+	assert.Len(t, diff.Files[0].Hunks[0].WholeRange.Lines, 16)
+}
+
+func numbers(lines []*DiffLine) []int {
+	var n []int
+	for _, l := range lines {
+		n = append(n, l.Number)
+	}
+	return n
+}
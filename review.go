@@ -0,0 +1,40 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+// ReviewAnchor identifies a changed line in the way GitHub's API for review
+// comments expects.
+type ReviewAnchor struct {
+	// Path is the name of the file the line is in.
+	Path string
+	// Position is the position of the line in the file's diff.
+	Position int
+	// Line is the number of the line on its Side of the diff.
+	Line int
+	// Side is "LEFT" for removed lines, or "RIGHT" for added lines.
+	Side string
+	// Mode is whether the line was added or removed.
+	Mode DiffLineMode
+}
+
+// ReviewAnchors returns an anchor for every added and removed line in the
+// diff, in the order they appear.
+func (d *Diff) ReviewAnchors() []ReviewAnchor {
+	var anchors []ReviewAnchor
+	d.ForEachChangedLineIncludingRemoved(func(file *DiffFile, line *DiffLine) {
+		anchor := ReviewAnchor{
+			Path:     file.name(),
+			Position: line.Position,
+			Side:     line.Side(),
+			Mode:     line.Mode,
+		}
+		if line.Mode == REMOVED {
+			anchor.Line = line.OrigNumber
+		} else {
+			anchor.Line = line.NewNumber
+		}
+		anchors = append(anchors, anchor)
+	})
+	return anchors
+}
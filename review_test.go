@@ -0,0 +1,43 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewAnchors(t *testing.T) {
+	diff, err := Parse(`diff --git a/main.go b/main.go
+index 504d2a1..50ccec3 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-import "fmt"
++import "log"
+ 
+@@ -10,2 +10,3 @@ func main() {
+ 	x := 1
++	y := 2
+ 	fmt.Println(x)
+diff --git a/old.go b/old.go
+deleted file mode 100644
+index 504d2a1..0000000
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package old
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ReviewAnchor{
+		{Path: "main.go", Position: 2, Line: 2, Side: "LEFT", Mode: REMOVED},
+		{Path: "main.go", Position: 3, Line: 2, Side: "RIGHT", Mode: ADDED},
+		{Path: "main.go", Position: 7, Line: 11, Side: "RIGHT", Mode: ADDED},
+		{Path: "old.go", Position: 1, Line: 1, Side: "LEFT", Mode: REMOVED},
+	}, diff.ReviewAnchors())
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// statWidth is the total width of a DiffStat table, matching git's default
+// when output is not a terminal.
+const statWidth = 80
+
+// DiffStat returns a summary of the diff in the same format as
+// "git diff --stat": one line per file with the number of changed lines and
+// a scaled histogram of insertions and deletions, followed by a line with
+// the totals. Binary files show "Bin" in place of the histogram, since the
+// diff does not record their sizes.
+func (d *Diff) DiffStat() string {
+	type stat struct {
+		name    string
+		added   int
+		removed int
+		binary  bool
+	}
+
+	stats := make([]stat, 0, len(d.Files))
+	maxLen, maxChange, numberWidth := 0, 0, 0
+	insertions, deletions := 0, 0
+	for _, f := range d.Files {
+		s := stat{name: statName(f), binary: f.Binary}
+		for _, h := range f.Hunks {
+			for _, l := range h.WholeRange.Lines {
+				switch l.Mode {
+				case ADDED:
+					s.added++
+				case REMOVED:
+					s.removed++
+				}
+			}
+		}
+		stats = append(stats, s)
+
+		if len(s.name) > maxLen {
+			maxLen = len(s.name)
+		}
+		if s.binary {
+			// Display change counts aligned with "Bin".
+			numberWidth = 3
+			continue
+		}
+		if s.added+s.removed > maxChange {
+			maxChange = s.added + s.removed
+		}
+		insertions += s.added
+		deletions += s.removed
+	}
+
+	// Share out the available width between the name and the graph in the
+	// same way as git, so that the output lines up with the real thing.
+	if w := len(strconv.Itoa(maxChange)); w > numberWidth {
+		numberWidth = w
+	}
+	width := statWidth
+	if width < 16+6+numberWidth {
+		width = 16 + 6 + numberWidth
+	}
+	graphWidth := maxChange
+	nameWidth := maxLen
+	if nameWidth+numberWidth+6+graphWidth > width {
+		if graphWidth > width*3/8-numberWidth-6 {
+			graphWidth = width*3/8 - numberWidth - 6
+			if graphWidth < 6 {
+				graphWidth = 6
+			}
+		}
+		if nameWidth > width-numberWidth-6-graphWidth {
+			nameWidth = width - numberWidth - 6 - graphWidth
+		} else {
+			graphWidth = width - numberWidth - 6 - nameWidth
+		}
+	}
+
+	var sb strings.Builder
+	for _, s := range stats {
+		fmt.Fprintf(&sb, " %-*s |", nameWidth, truncateStatName(s.name, nameWidth))
+		if s.binary {
+			fmt.Fprintf(&sb, " %*s\n", numberWidth, "Bin")
+			continue
+		}
+
+		total := s.added + s.removed
+		fmt.Fprintf(&sb, " %*d", numberWidth, total)
+		if total > 0 {
+			sb.WriteString(" ")
+		}
+
+		add, del := s.added, s.removed
+		if graphWidth <= maxChange {
+			scaled := scaleLinear(total, graphWidth, maxChange)
+			if scaled < 2 && add > 0 && del > 0 {
+				scaled = 2
+			}
+			if add < del {
+				add = scaleLinear(add, graphWidth, maxChange)
+				del = scaled - add
+			} else {
+				del = scaleLinear(del, graphWidth, maxChange)
+				add = scaled - del
+			}
+		}
+		sb.WriteString(strings.Repeat("+", add))
+		sb.WriteString(strings.Repeat("-", del))
+		sb.WriteString("\n")
+	}
+
+	files := len(d.Files)
+	fmt.Fprintf(&sb, " %d %s changed", files, plural(files, "file", "files"))
+	if insertions > 0 || deletions == 0 {
+		fmt.Fprintf(&sb, ", %d %s(+)", insertions, plural(insertions, "insertion", "insertions"))
+	}
+	if deletions > 0 || insertions == 0 {
+		fmt.Fprintf(&sb, ", %d %s(-)", deletions, plural(deletions, "deletion", "deletions"))
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// statName returns the name git shows for a file in a diffstat, which for
+// renames includes both the old and the new name.
+func statName(f *DiffFile) string {
+	if f.OrigName == "" || f.NewName == "" || f.OrigName == f.NewName {
+		if f.NewName != "" {
+			return f.NewName
+		}
+		return f.OrigName
+	}
+	return renameName(f.OrigName, f.NewName)
+}
+
+// renameName formats a rename as "a => b", factoring out any common leading
+// and trailing directories as "dir/{a => b}/rest", like git does.
+func renameName(a, b string) string {
+	// Find the common prefix, which must end in a slash.
+	pfx := 0
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '/' {
+			pfx = i + 1
+		}
+	}
+
+	// Find the common suffix, which must start with a slash. If there is a
+	// common prefix, we let this run into its final slash.
+	sfx := 0
+	adjust := 0
+	if pfx > 0 {
+		adjust = 1
+	}
+	for i, j := len(a)-1, len(b)-1; i >= pfx-adjust && j >= pfx-adjust && a[i] == b[j]; i, j = i-1, j-1 {
+		if a[i] == '/' {
+			sfx = len(a) - i
+		}
+	}
+
+	if pfx+sfx == 0 {
+		return a + " => " + b
+	}
+	aMid := len(a) - pfx - sfx
+	if aMid < 0 {
+		aMid = 0
+	}
+	bMid := len(b) - pfx - sfx
+	if bMid < 0 {
+		bMid = 0
+	}
+	return a[:pfx] + "{" + a[pfx:pfx+aMid] + " => " + b[pfx:pfx+bMid] + "}" + a[len(a)-sfx:]
+}
+
+// truncateStatName shortens name to fit in width, replacing the start with
+// "..." and cutting back to a directory boundary where possible.
+func truncateStatName(name string, width int) string {
+	if len(name) <= width {
+		return name
+	}
+	width -= 3
+	if width < 0 {
+		width = 0
+	}
+	name = name[len(name)-width:]
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		name = name[i:]
+	}
+	return "..." + name
+}
+
+// scaleLinear scales n from the range [0, max] to [0, width], ensuring any
+// non-zero value gets at least one column.
+func scaleLinear(n, width, max int) int {
+	if n == 0 {
+		return 0
+	}
+	return 1 + n*(width-1)/max
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+var (
+	reStatSummary    = regexp.MustCompile(`^\d+ files? changed`)
+	reCompactSummary = regexp.MustCompile(`^(.*) \(((?:new|gone|mode)(?: [+-][xl])?)\)$`)
+)
+
+// ParseCompactSummary parses the table from "git diff --compact-summary",
+// which is like "git diff --stat", but marks added and deleted files with
+// "(new)" and "(gone)", and changes to their modes like "(mode +x)". The
+// files in the returned diff have no hunks. Names that were shortened to
+// fit in the table are left as they are.
+func ParseCompactSummary(s string) (*Diff, error) {
+	diff := &Diff{Raw: s}
+	for i, l := range strings.Split(s, "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || reStatSummary.MatchString(l) {
+			continue
+		}
+		name, stat, ok := strings.Cut(l, " | ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: not a summary line: %q", i+1, l)
+		}
+		name = strings.TrimSpace(name)
+
+		file := &DiffFile{
+			Mode:   MODIFIED,
+			Binary: strings.HasPrefix(strings.TrimSpace(stat), "Bin"),
+		}
+		if m := reCompactSummary.FindStringSubmatch(name); m != nil {
+			name = m[1]
+			kind, flag, _ := strings.Cut(m[2], " ")
+			mode := "100644"
+			switch flag {
+			case "+x":
+				mode = "100755"
+			case "+l":
+				mode = "120000"
+			}
+			switch kind {
+			case "new":
+				file.Mode = NEW
+				file.NewMode = mode
+			case "gone":
+				file.Mode = DELETED
+				file.OldMode = mode
+			case "mode":
+				switch flag {
+				case "-x":
+					file.OldMode, file.NewMode = "100755", "100644"
+				case "-l":
+					file.OldMode, file.NewMode = "120000", "100644"
+				default:
+					file.OldMode, file.NewMode = "100644", mode
+				}
+			}
+		}
+
+		file.OrigName, file.NewName = name, name
+		if from, to, ok := splitRenameName(name); ok {
+			file.Mode = RENAMED
+			file.OrigName, file.NewName = from, to
+		}
+		diff.Files = append(diff.Files, file)
+	}
+	return diff, nil
+}
+
+// splitRenameName splits a name in the form returned by renameName, such as
+// "dir/{a => b}/rest", into the original and new names.
+func splitRenameName(name string) (from, to string, ok bool) {
+	start, end := strings.Index(name, "{"), strings.LastIndex(name, "}")
+	if start >= 0 && end > start {
+		a, b, ok := strings.Cut(name[start+1:end], " => ")
+		if !ok {
+			return "", "", false
+		}
+		prefix, suffix := name[:start], name[end+1:]
+		// An empty part leaves a doubled slash, as in "{ => dir}/file".
+		from = strings.ReplaceAll(prefix+a+suffix, "//", "/")
+		to = strings.ReplaceAll(prefix+b+suffix, "//", "/")
+		return from, to, true
+	}
+	return strings.Cut(name, " => ")
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffStat(t *testing.T) {
+	byt, err := os.ReadFile("testdata/stat.diff")
+	require.NoError(t, err)
+	diff, err := Parse(string(byt))
+	require.NoError(t, err)
+
+	// Generated with "git diff --stat", except that we don't know the size
+	// of binary files.
+	expected, err := os.ReadFile("testdata/stat.txt")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), diff.DiffStat())
+}
+
+func TestDiffStatRename(t *testing.T) {
+	for _, tc := range []struct {
+		orig, new string
+		expected  string
+	}{
+		{orig: "old", new: "new", expected: "old => new"},
+		{orig: "dir/old", new: "dir/new", expected: "dir/{old => new}"},
+		{orig: "a/file", new: "b/file", expected: "{a => b}/file"},
+		{orig: "a/x/file", new: "a/y/file", expected: "a/{x => y}/file"},
+		{orig: "a/file", new: "a/b/file", expected: "a/{ => b}/file"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, renameName(tc.orig, tc.new))
+		})
+	}
+}
+
+func TestParseCompactSummary(t *testing.T) {
+	diff, err := ParseCompactSummary(` bin.dat (new)       | Bin 0 -> 2 bytes
+ gone.txt (gone)     |   1 -
+ link (new +l)       |   1 +
+ link2 (mode +l)     |   2 +-
+ link3 (mode -l)     |   2 +-
+ mod.txt             |   1 +
+ new.txt (new)       |   1 +
+ run.sh (new +x)     |   1 +
+ script.sh (mode +x) |   0
+ src/{a.go => b.go}  |   1 +
+ tool.sh (mode -x)   |   0
+ 11 files changed, 7 insertions(+), 3 deletions(-)
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*DiffFile{
+		{Mode: NEW, OrigName: "bin.dat", NewName: "bin.dat", Binary: true, NewMode: "100644"},
+		{Mode: DELETED, OrigName: "gone.txt", NewName: "gone.txt", OldMode: "100644"},
+		{Mode: NEW, OrigName: "link", NewName: "link", NewMode: "120000"},
+		{Mode: MODIFIED, OrigName: "link2", NewName: "link2", OldMode: "100644", NewMode: "120000"},
+		{Mode: MODIFIED, OrigName: "link3", NewName: "link3", OldMode: "120000", NewMode: "100644"},
+		{Mode: MODIFIED, OrigName: "mod.txt", NewName: "mod.txt"},
+		{Mode: NEW, OrigName: "new.txt", NewName: "new.txt", NewMode: "100644"},
+		{Mode: NEW, OrigName: "run.sh", NewName: "run.sh", NewMode: "100755"},
+		{Mode: MODIFIED, OrigName: "script.sh", NewName: "script.sh", OldMode: "100644", NewMode: "100755"},
+		{Mode: RENAMED, OrigName: "src/a.go", NewName: "src/b.go"},
+		{Mode: MODIFIED, OrigName: "tool.sh", NewName: "tool.sh", OldMode: "100755", NewMode: "100644"},
+	}, diff.Files)
+
+	_, err = ParseCompactSummary("diff --git a/file b/file\n")
+	assert.Error(t, err)
+}
+
+func TestSplitRenameName(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		from, to string
+	}{
+		{name: "a => b", from: "a", to: "b"},
+		{name: "src/{a.go => b.go}", from: "src/a.go", to: "src/b.go"},
+		{name: "{old => new}/file.go", from: "old/file.go", to: "new/file.go"},
+		{name: "a/{ => b}/c", from: "a/c", to: "a/b/c"},
+	} {
+		from, to, ok := splitRenameName(tc.name)
+		assert.True(t, ok, tc.name)
+		assert.Equal(t, tc.from, from, tc.name)
+		assert.Equal(t, tc.to, to, tc.name)
+		assert.Equal(t, tc.name, renameName(from, to))
+	}
+
+	_, _, ok := splitRenameName("file.go")
+	assert.False(t, ok)
+}
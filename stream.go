@@ -0,0 +1,175 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// StreamParser parses a diff incrementally as it's written, such as while
+// reading the output of a running "git diff". Incomplete lines are buffered
+// until the rest of the line is written. The zero value is ready to use.
+type StreamParser struct {
+	// Parser holds the options used for parsing.
+	Parser Parser
+
+	state   *parser
+	raw     strings.Builder
+	partial []byte
+	err     error
+}
+
+// Write parses the complete lines in b, and buffers any incomplete line at
+// the end. It only returns an error if the diff couldn't be parsed, in which
+// case all later writes will fail.
+func (s *StreamParser) Write(b []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.state == nil {
+		s.state = newParser(&s.Parser)
+	}
+	s.raw.Write(b)
+
+	n := len(b)
+	for {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			break
+		}
+		s.partial = append(s.partial, b[:i]...)
+		b = b[i+1:]
+		if err := s.state.parseLine(string(s.partial)); err != nil {
+			s.err = err
+			return 0, err
+		}
+		s.partial = s.partial[:0]
+	}
+	s.partial = append(s.partial, b...)
+
+	return n, nil
+}
+
+// Diff returns the diff parsed from the complete lines written so far. It
+// continues to be updated by later writes, so shouldn't be modified.
+func (s *StreamParser) Diff() *Diff {
+	if s.state == nil {
+		return &Diff{}
+	}
+	s.state.diff.Raw = s.raw.String()
+	return s.state.diff
+}
+
+// Flush parses any incomplete line that's been written, and returns the
+// whole diff, which is the same as if it had been parsed with Parse. The
+// StreamParser is then reset, ready to parse a new diff.
+func (s *StreamParser) Flush() (*Diff, error) {
+	defer s.reset()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.state == nil {
+		s.state = newParser(&s.Parser)
+	}
+	// Like Parse, treat the end of the diff as the end of a line, even if
+	// it's empty.
+	if err := s.state.parseLine(string(s.partial)); err != nil {
+		return nil, err
+	}
+	s.state.diff.Raw = s.raw.String()
+	return s.state.finish(), nil
+}
+
+func (s *StreamParser) reset() {
+	s.state = nil
+	s.raw.Reset()
+	s.partial = nil
+	s.err = nil
+}
+
+// ScanLines parses the diff read from r, calling fn with each line of its
+// hunks and the name of the file it's in as they're read, and stops if fn
+// returns an error. Unlike ParseReader, the files and hunks aren't kept, so
+// it only needs memory for a few lines at a time. Each line is only passed
+// to fn once the line after it has been read, so that NoNewline is set.
+func ScanLines(r io.Reader, fn func(file string, line *DiffLine) error) error {
+	return (&Parser{}).ScanLines(r, fn)
+}
+
+// ScanLines is like the package-level ScanLines, but parses the diff with
+// the Parser's options.
+func (p *Parser) ScanLines(r io.Reader, fn func(file string, line *DiffLine) error) error {
+	s := &lineScanner{state: newParser(p), fn: fn}
+	err := p.readLines(r, func(line, _ string) error {
+		return s.parseLine(line)
+	})
+	if err != nil {
+		return err
+	}
+	return s.emit(0)
+}
+
+// lineScanner passes the lines of the hunks to fn as they're parsed, and
+// then forgets them.
+type lineScanner struct {
+	state *parser
+	fn    func(file string, line *DiffLine) error
+
+	// file and hunk are the ones the lines not yet passed to fn are in.
+	file *DiffFile
+	hunk *DiffHunk
+}
+
+func (s *lineScanner) parseLine(l string) error {
+	if err := s.state.parseLine(l); err != nil {
+		return err
+	}
+	if s.state.hunk != s.hunk {
+		if err := s.emit(0); err != nil {
+			return err
+		}
+		s.file, s.hunk = s.state.file, s.state.hunk
+		// Only the current file and hunk are needed to carry on parsing.
+		if s.file != nil {
+			s.state.diff.Files = []*DiffFile{s.file}
+		}
+		if s.hunk != nil {
+			s.file.Hunks = []*DiffHunk{s.hunk}
+		}
+	}
+	// Keep the last line, which a "\ No newline at end of file" line could
+	// still apply to.
+	return s.emit(1)
+}
+
+// emit passes all but the last keep lines of the current hunk to fn, and
+// removes them from the hunk.
+func (s *lineScanner) emit(keep int) error {
+	if s.hunk == nil {
+		return nil
+	}
+	lines := s.hunk.WholeRange.Lines
+	if len(lines) <= keep {
+		return nil
+	}
+	for _, l := range lines[:len(lines)-keep] {
+		if err := s.fn(s.file.name(), l); err != nil {
+			return err
+		}
+	}
+	s.hunk.WholeRange.Lines = lines[len(lines)-keep:]
+	s.hunk.OrigRange.Lines = lastLines(s.hunk.OrigRange.Lines, keep)
+	s.hunk.NewRange.Lines = lastLines(s.hunk.NewRange.Lines, keep)
+	return nil
+}
+
+func lastLines(lines []*DiffLine, n int) []*DiffLine {
+	if len(lines) <= n {
+		return lines
+	}
+	return lines[len(lines)-n:]
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamParser(t *testing.T) {
+	byt, err := os.ReadFile("example.diff")
+	require.NoError(t, err)
+	expected, err := Parse(string(byt))
+	require.NoError(t, err)
+
+	for _, size := range []int{1, 2, 7, 64, len(byt)} {
+		var stream StreamParser
+		for i := 0; i < len(byt); i += size {
+			end := i + size
+			if end > len(byt) {
+				end = len(byt)
+			}
+			n, err := stream.Write(byt[i:end])
+			require.NoError(t, err)
+			require.Equal(t, end-i, n)
+		}
+
+		diff, err := stream.Flush()
+		require.NoError(t, err)
+		assert.Equal(t, expected, diff, "chunk size %d", size)
+	}
+}
+
+func TestStreamParserPartial(t *testing.T) {
+	var stream StreamParser
+
+	_, err := stream.Write([]byte("diff --git a/file1 b/file1\nindex 504d2a1..50ccec3 100644\n--- a/fi"))
+	require.NoError(t, err)
+	require.Len(t, stream.Diff().Files, 1)
+	assert.Equal(t, "file1", stream.Diff().Files[0].NewName)
+	assert.Empty(t, stream.Diff().Files[0].Hunks)
+
+	_, err = stream.Write([]byte("le1\n+++ b/file1\n@@ -1 +1 @@\n-old\n+ne"))
+	require.NoError(t, err)
+	require.Len(t, stream.Diff().Files[0].Hunks, 1)
+	assert.Len(t, stream.Diff().Files[0].Hunks[0].WholeRange.Lines, 1)
+
+	diff, err := stream.Flush()
+	require.NoError(t, err)
+	require.Len(t, diff.Files[0].Hunks[0].WholeRange.Lines, 2)
+	assert.Equal(t, "ne", diff.Files[0].Hunks[0].WholeRange.Lines[1].Content)
+
+	// After flushing, a new diff can be written.
+	_, err = stream.Write([]byte("diff --git a/file2 b/file2\n"))
+	require.NoError(t, err)
+	diff, err = stream.Flush()
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, "file2", diff.Files[0].NewName)
+}
+
+func TestScanLines(t *testing.T) {
+	byt, err := os.ReadFile("example.diff")
+	require.NoError(t, err)
+	diff, err := Parse(string(byt))
+	require.NoError(t, err)
+
+	var expected []string
+	for _, f := range diff.Files {
+		for _, h := range f.Hunks {
+			for _, l := range h.WholeRange.Lines {
+				expected = append(expected, fmt.Sprintf("%s:%d:%s", f.name(), l.Position, l.Content))
+			}
+		}
+	}
+
+	var lines []string
+	err = ScanLines(bytes.NewReader(byt), func(file string, line *DiffLine) error {
+		lines = append(lines, fmt.Sprintf("%s:%d:%s", file, line.Position, line.Content))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, lines)
+
+	stop := errors.New("stop")
+	count := 0
+	err = ScanLines(bytes.NewReader(byt), func(file string, line *DiffLine) error {
+		count++
+		if count == 3 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestScanLinesNoNewline(t *testing.T) {
+	var noNewline []bool
+	err := ScanLines(strings.NewReader(`--- a/file
++++ b/file
+@@ -1 +1 @@
+-old
+\ No newline at end of file
++new
+\ No newline at end of file
+`), func(file string, line *DiffLine) error {
+		assert.Equal(t, "file", file)
+		noNewline = append(noNewline, line.NoNewline)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, noNewline)
+}
+
+func TestScanLinesMaxLineBytes(t *testing.T) {
+	input := "--- a/file\n+++ b/file\n@@ -1 +1 @@\n-" + strings.Repeat("x", 100) + "\n+new\n"
+	parser := Parser{MaxLineBytes: 50}
+	err := parser.ScanLines(strings.NewReader(input), func(file string, line *DiffLine) error {
+		return nil
+	})
+	require.EqualError(t, err, "line 4 is longer than the maximum of 50 bytes")
+
+	parser.MaxLineBytes = 200
+	count := 0
+	err = parser.ScanLines(strings.NewReader(input), func(file string, line *DiffLine) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import "strings"
+
+// WhitespaceErrorKind is a kind of whitespace error that "git diff --check"
+// looks for.
+type WhitespaceErrorKind string
+
+const (
+	// TRAILING_WHITESPACE if the line ends with spaces or tabs
+	TRAILING_WHITESPACE WhitespaceErrorKind = "trailing-whitespace"
+	// SPACE_BEFORE_TAB if the line's indent has a space before a tab
+	SPACE_BEFORE_TAB WhitespaceErrorKind = "space-before-tab"
+)
+
+// WhitespaceError is a whitespace error in an added line.
+type WhitespaceError struct {
+	// File is the new name of the file the line was added to, and Line is
+	// its number in the new version of the file.
+	File string
+	Line int
+	Kind WhitespaceErrorKind
+}
+
+// WhitespaceErrors returns the whitespace errors in the lines added by the
+// diff, like "git diff --check". A line with both kinds of errors has an
+// entry for each.
+func (d *Diff) WhitespaceErrors() []WhitespaceError {
+	var errs []WhitespaceError
+	d.ForEachChangedLine(func(file *DiffFile, line *DiffLine) {
+		if strings.TrimRight(line.Content, " \t") != line.Content {
+			errs = append(errs, WhitespaceError{File: file.NewName, Line: line.Number, Kind: TRAILING_WHITESPACE})
+		}
+		indent := line.Content[:len(line.Content)-len(strings.TrimLeft(line.Content, " \t"))]
+		if strings.Contains(indent, " \t") {
+			errs = append(errs, WhitespaceError{File: file.NewName, Line: line.Number, Kind: SPACE_BEFORE_TAB})
+		}
+	})
+	return errs
+}
+
+// IgnoreWhitespace returns a copy of the diff without changes that only
+// add, remove or change whitespace within lines, like "git diff -w". In
+// each block of removed lines followed by added lines, removed lines are
+// paired in order with the first matching added lines, and each pair becomes
+// a single unchanged line, with the content of the added line. Hunks left
+// without any changes are dropped, along with files left without any hunks,
+// unless they were added, deleted, renamed or copied, or their mode changed.
+func (d *Diff) IgnoreWhitespace() *Diff {
+	out := *d
+	out.Files = nil
+	for _, f := range d.Files {
+		file := *f
+		file.Hunks = nil
+		// The file isn't in the new Raw in the same place.
+		file.RawStart, file.RawEnd = 0, 0
+		for _, h := range f.Hunks {
+			if h := h.ignoreWhitespace(); h != nil {
+				file.Hunks = append(file.Hunks, h)
+			}
+		}
+		if len(f.Hunks) > 0 && len(file.Hunks) == 0 && file.Mode == MODIFIED && file.OldMode == file.NewMode {
+			continue
+		}
+		file.renumberPositions()
+		out.Files = append(out.Files, &file)
+	}
+	out.Raw = out.String()
+	return &out
+}
+
+// ignoreWhitespace returns a copy of the hunk with the removed and added
+// lines that only differ in whitespace made unchanged, as described by
+// Diff.IgnoreWhitespace, or nil if no changes are left.
+func (hunk *DiffHunk) ignoreWhitespace() *DiffHunk {
+	c := &DiffHunk{
+		HunkHeader: hunk.HunkHeader,
+	}
+	origNumber, newNumber := hunk.OrigRange.first(), hunk.NewRange.first()
+	add := func(l *DiffLine, mode DiffLineMode) {
+		line := *l
+		line.Mode = mode
+		c.addLine(line, &origNumber, &newNumber, false)
+	}
+
+	changed := false
+	lines := hunk.WholeRange.Lines
+	for i := 0; i < len(lines); {
+		if lines[i].Mode == UNCHANGED {
+			add(lines[i], UNCHANGED)
+			i++
+			continue
+		}
+		removedStart := i
+		for i < len(lines) && lines[i].Mode == REMOVED {
+			i++
+		}
+		addedStart := i
+		for i < len(lines) && lines[i].Mode == ADDED {
+			i++
+		}
+		removed, added := lines[removedStart:addedStart], lines[addedStart:i]
+
+		// Lines between the pairs are still changes, which keep their
+		// order around the pairs.
+		r, a := 0, 0
+		for _, p := range whitespacePairs(removed, added) {
+			for ; r < p[0]; r++ {
+				add(removed[r], REMOVED)
+				changed = true
+			}
+			for ; a < p[1]; a++ {
+				add(added[a], ADDED)
+				changed = true
+			}
+			add(added[a], UNCHANGED)
+			r, a = r+1, a+1
+		}
+		for ; r < len(removed); r++ {
+			add(removed[r], REMOVED)
+			changed = true
+		}
+		for ; a < len(added); a++ {
+			add(added[a], ADDED)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	c.OrigRange.resize(hunk.OrigRange.first(), len(c.OrigRange.Lines))
+	c.NewRange.resize(hunk.NewRange.first(), len(c.NewRange.Lines))
+	return c
+}
+
+// whitespacePairs returns the indexes of the removed and added lines that
+// are the same apart from whitespace, pairing each removed line in turn with
+// the first matching added line after the last pair.
+func whitespacePairs(removed, added []*DiffLine) [][2]int {
+	var pairs [][2]int
+	next := 0
+	for i, r := range removed {
+		for j := next; j < len(added); j++ {
+			if withoutWhitespace(r.Content) == withoutWhitespace(added[j].Content) {
+				pairs = append(pairs, [2]int{i, j})
+				next = j + 1
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+func withoutWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
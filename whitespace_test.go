@@ -0,0 +1,111 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhitespaceErrors(t *testing.T) {
+	diff, err := Parse("diff --git a/main.go b/main.go\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -1,4 +1,8 @@\n" +
+		" func main() {\n" +
+		"-\tfmt.Println(\"hi\") \n" +
+		"+\tfmt.Println(\"hi\")\n" +
+		"+\tx := 1 \n" +
+		"+ \ty := 2\n" +
+		"+  \t z := 3\t\n" +
+		" \tw := 4 \n" +
+		"+\tv := \"a \tb\"\n" +
+		" }\n")
+	require.NoError(t, err)
+
+	assert.Equal(t, []WhitespaceError{
+		{File: "main.go", Line: 3, Kind: TRAILING_WHITESPACE},
+		{File: "main.go", Line: 4, Kind: SPACE_BEFORE_TAB},
+		{File: "main.go", Line: 5, Kind: TRAILING_WHITESPACE},
+		{File: "main.go", Line: 5, Kind: SPACE_BEFORE_TAB},
+	}, diff.WhitespaceErrors())
+
+	assert.Empty(t, setup(t).WhitespaceErrors())
+}
+
+func TestIgnoreWhitespace(t *testing.T) {
+	diff, err := Parse(`diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,4 @@
+ func main() {
+-	x := 1
+-	fmt.Println(x)
++	x  :=  1
++	fmt.Println(x + 1)
+ }
+diff --git a/indent.go b/indent.go
+index 3333333..4444444 100644
+--- a/indent.go
++++ b/indent.go
+@@ -1,2 +1,2 @@
+-if ok {
++if ok  {
+ }
+`)
+	require.NoError(t, err)
+
+	ignored := diff.IgnoreWhitespace()
+	require.Len(t, ignored.Files, 1)
+	assert.Equal(t, `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,4 @@
+ func main() {
+ 	x  :=  1
+-	fmt.Println(x)
++	fmt.Println(x + 1)
+ }
+`, ignored.String())
+	require.NoError(t, ignored.Files[0].CheckPositions())
+
+	// The original diff is unchanged.
+	assert.Len(t, diff.Files, 2)
+	assert.Len(t, diff.Files[0].Hunks[0].WholeRange.Lines, 6)
+}
+
+func TestIgnoreWhitespaceAfterChange(t *testing.T) {
+	diff, err := Parse(`--- a/file
++++ b/file
+@@ -1,3 +1,3 @@
+ ctx
+-a = 1
+-if  x {
++a = 2
++if x {
+`)
+	require.NoError(t, err)
+
+	hunk := diff.IgnoreWhitespace().Files[0].Hunks[0]
+	var orig, updated []string
+	for _, l := range hunk.OrigRange.Lines {
+		orig = append(orig, l.Content)
+	}
+	for _, l := range hunk.NewRange.Lines {
+		updated = append(updated, l.Content)
+	}
+	assert.Equal(t, []string{"ctx", "a = 1", "if x {"}, orig)
+	assert.Equal(t, []string{"ctx", "a = 2", "if x {"}, updated)
+	assert.Equal(t, []int{1, 2, 3}, numbers(hunk.NewRange.Lines))
+	assert.Equal(t, `@@ -1,3 +1,3 @@
+ ctx
+-a = 1
++a = 2
+ if x {
+`, hunk.String())
+}